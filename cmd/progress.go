@@ -0,0 +1,122 @@
+// Package cmd holds the pieces of the s3fs command line tool shared by
+// its subcommands: progress display and, later, the mount drivers.
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ThierryZhou/go-s3fs/fs"
+	"github.com/ThierryZhou/go-s3fs/s3"
+)
+
+const progressInterval = 500 * time.Millisecond
+
+var (
+	progressMu sync.Mutex
+	// nlines is how many lines the previous refresh printed, so the next
+	// one knows how far to move the cursor back up.
+	nlines    int
+	transfers = map[string]*s3.S3Node{}
+)
+
+// AddTransfer registers a transfer with the progress display; RemoveTransfer
+// drops it again once it completes.
+func AddTransfer(name string, sno *s3.S3Node) {
+	progressMu.Lock()
+	transfers[name] = sno
+	progressMu.Unlock()
+}
+
+func RemoveTransfer(name string) {
+	progressMu.Lock()
+	delete(transfers, name)
+	progressMu.Unlock()
+}
+
+// StartProgress begins the periodic in-place rendering and returns a
+// function that stops it and prints a final state.
+func StartProgress() func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				printProgress("")
+				return
+			case <-ticker.C:
+				printProgress("")
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// stdoutIsTerminal reports whether stdout goes to a terminal; redirected
+// output gets plain appended lines instead of cursor movement.
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// statsLines renders one line per active transfer.
+func statsLines() []string {
+	names := make([]string, 0, len(transfers))
+	for name := range transfers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		st := transfers[name].Snapshot()
+		line := fmt.Sprintf("%s: %s", name, fs.SizeSuffix(st.Bytes))
+		if st.Size > 0 {
+			line += fmt.Sprintf("/%s (%d%%)", fs.SizeSuffix(st.Size), st.Bytes*100/st.Size)
+		}
+		line += fmt.Sprintf(", %s/s, %v", fs.SizeSuffix(int64(st.Rate)), st.Elapsed.Round(time.Second))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// printProgress redraws the transfer status, first erasing the previous
+// refresh with ANSI cursor-up sequences. An optional log message is
+// emitted above the stats so it scrolls away normally.
+func printProgress(logMessage string) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	var buf bytes.Buffer
+	terminal := stdoutIsTerminal()
+	if terminal {
+		for i := 0; i < nlines; i++ {
+			buf.WriteString("\x1b[1A\x1b[2K")
+		}
+	}
+	if logMessage != "" {
+		buf.WriteString(logMessage + "\n")
+	}
+	lines := statsLines()
+	for _, line := range lines {
+		buf.WriteString(line + "\n")
+	}
+	if terminal {
+		nlines = len(lines)
+	} else {
+		nlines = 0
+	}
+	os.Stdout.Write(buf.Bytes())
+}
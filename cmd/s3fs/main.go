@@ -0,0 +1,276 @@
+// Command s3fs mounts either an S3 target (with -args) or, as a local
+// testing stand-in, a directory tree through a loopback FUSE driver that
+// refuses to remove or rename files while they are still open, mirroring
+// the busy semantics of the object-store uploads that happen on release.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	"github.com/ThierryZhou/go-s3fs/s3"
+)
+
+var (
+	debug = flag.Bool("debug", false, "print debugging messages")
+	delay = flag.Duration("delay", 500*time.Millisecond,
+		"how long remove and rename wait for open handles to be released before returning EBUSY")
+	connArgs = flag.String("args", "",
+		"S3 connection string (url=...,accesskey=...,secretkey=...); when set, TARGET is bucket[/prefix] on the object store — or empty for all buckets — instead of a local directory")
+	list = flag.Bool("list", false,
+		"list buckets (or TARGET's keys when given) to stdout and exit without mounting; requires -args")
+)
+
+// runList prints what the credentials can see: all buckets when target
+// is empty, otherwise one level of target's keys. Exercising the
+// connection this way is the quickest check that args are right before
+// committing to a mount.
+func runList(target string) error {
+	opt := s3.ParseOption(*connArgs)
+	cli, err := s3.NewS3Client(opt)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx := context.Background()
+	userID := opt.UserID
+	if target == "" {
+		buckets, err := cli.ListBuckets(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, b := range buckets {
+			fmt.Printf("%s\t%s\n", b.CreatedAt.Format(time.RFC3339), b.Name)
+		}
+		return nil
+	}
+	bucket, prefix, _ := strings.Cut(target, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	objs, err := cli.ListObject(ctx, userID, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if obj.IsDir {
+			fmt.Printf("DIR\t\t%s\n", obj.Key)
+			continue
+		}
+		fmt.Printf("%s\t%d\t%s\n", obj.LastModified.Format(time.RFC3339), obj.Size, obj.Key)
+	}
+	return nil
+}
+
+// openTracker counts open handles per path. Unlink and Rename used to
+// sleep a fixed interval and re-check, which missed the kernel's
+// asynchronous close more often than not; instead the tracker's condition
+// variable is signalled on every release so waiters wake exactly when the
+// count they care about can have changed.
+type openTracker struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	opens map[string]int
+}
+
+func newOpenTracker() *openTracker {
+	t := &openTracker{opens: make(map[string]int)}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+func (t *openTracker) inc(path string) {
+	t.mu.Lock()
+	t.opens[path]++
+	t.mu.Unlock()
+}
+
+func (t *openTracker) dec(path string) {
+	t.mu.Lock()
+	if t.opens[path] <= 1 {
+		delete(t.opens, path)
+	} else {
+		t.opens[path]--
+	}
+	t.mu.Unlock()
+	t.cond.Broadcast()
+}
+
+// rename moves the open count from old to new so handles opened before a
+// rename still block removal of the file under its new name.
+func (t *openTracker) rename(oldPath, newPath string) {
+	t.mu.Lock()
+	if n, ok := t.opens[oldPath]; ok {
+		delete(t.opens, oldPath)
+		t.opens[newPath] += n
+	}
+	t.mu.Unlock()
+	t.cond.Broadcast()
+}
+
+// waitIdle blocks until no handle on path is open or timeout passes,
+// reporting whether the path became idle. A path with no open handles
+// returns immediately.
+func (t *openTracker) waitIdle(path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() { t.cond.Broadcast() })
+	defer timer.Stop()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.opens[path] > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		t.cond.Wait()
+	}
+	return true
+}
+
+// busyNode is a loopback node that tracks open handles and makes
+// Unlink/Rename wait for pending releases.
+type busyNode struct {
+	fs.LoopbackNode
+
+	tracker *openTracker
+}
+
+var _ = (fs.NodeOpener)((*busyNode)(nil))
+var _ = (fs.NodeCreater)((*busyNode)(nil))
+var _ = (fs.NodeReleaser)((*busyNode)(nil))
+var _ = (fs.NodeUnlinker)((*busyNode)(nil))
+var _ = (fs.NodeRenamer)((*busyNode)(nil))
+
+// relPath is this node's path below the mount root.
+func (n *busyNode) relPath() string {
+	return n.Path(n.Root())
+}
+
+func (n *busyNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	fh, fuseFlags, errno := n.LoopbackNode.Open(ctx, flags)
+	if errno == 0 {
+		n.tracker.inc(n.relPath())
+	}
+	return fh, fuseFlags, errno
+}
+
+func (n *busyNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	inode, fh, fuseFlags, errno := n.LoopbackNode.Create(ctx, name, flags, mode, out)
+	if errno == 0 {
+		n.tracker.inc(filepath.Join(n.relPath(), name))
+	}
+	return inode, fh, fuseFlags, errno
+}
+
+func (n *busyNode) Release(ctx context.Context, fh fs.FileHandle) syscall.Errno {
+	var errno syscall.Errno
+	if fr, ok := fh.(fs.FileReleaser); ok {
+		errno = fr.Release(ctx)
+	}
+	n.tracker.dec(n.relPath())
+	return errno
+}
+
+func (n *busyNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	path := filepath.Join(n.relPath(), name)
+	if !n.tracker.waitIdle(path, *delay) {
+		return syscall.EBUSY
+	}
+	return n.LoopbackNode.Unlink(ctx, name)
+}
+
+func (n *busyNode) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	oldPath := filepath.Join(n.relPath(), name)
+	if !n.tracker.waitIdle(oldPath, *delay) {
+		return syscall.EBUSY
+	}
+	errno := n.LoopbackNode.Rename(ctx, name, newParent, newName, flags)
+	if errno == 0 {
+		np, ok := newParent.(*busyNode)
+		if !ok {
+			return errno
+		}
+		n.tracker.rename(oldPath, filepath.Join(np.relPath(), newName))
+	}
+	return errno
+}
+
+// loopbackRoot builds the local-directory stand-in tree rooted at orig.
+func loopbackRoot(orig string) fs.InodeEmbedder {
+	tracker := newOpenTracker()
+	root := &fs.LoopbackRoot{
+		Path: orig,
+		NewNode: func(rootData *fs.LoopbackRoot, parent *fs.Inode, name string, st *syscall.Stat_t) fs.InodeEmbedder {
+			return &busyNode{
+				LoopbackNode: fs.LoopbackNode{RootData: rootData},
+				tracker:      tracker,
+			}
+		},
+	}
+	return root.NewNode(root, nil, "", nil)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] TARGET MOUNTPOINT\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *list {
+		if *connArgs == "" {
+			log.Fatal("-list requires -args")
+		}
+		if err := runList(flag.Arg(0)); err != nil {
+			log.Fatalf("list: %v", err)
+		}
+		return
+	}
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var rootNode fs.InodeEmbedder
+	opts := &fs.Options{}
+	if *connArgs != "" {
+		node, err := s3.NewS3FileSystem(flag.Arg(0), *connArgs)
+		if err != nil {
+			log.Fatalf("connect %s: %v", flag.Arg(0), err)
+		}
+		rootNode = node
+		opts = s3.MountOptions(s3.ParseOption(*connArgs))
+		opts.MountOptions.FsName = "s3:" + flag.Arg(0)
+	} else {
+		orig, err := filepath.Abs(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("resolve %s: %v", flag.Arg(0), err)
+		}
+		rootNode = loopbackRoot(orig)
+		opts.MountOptions.FsName = orig
+		opts.MountOptions.Name = "s3fs"
+	}
+	opts.Debug = *debug
+	server, err := fs.Mount(flag.Arg(1), rootNode, opts)
+	if err != nil {
+		log.Fatalf("mount %s: %v", flag.Arg(1), err)
+	}
+	server.Wait()
+	// Roots that hold backend resources (the S3 tree does) clean up once
+	// the kernel is done with the mount.
+	if closer, ok := rootNode.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("close root: %v", err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUnlinkAfterCloseNotBusy is the open/close/unlink sequence: once the
+// release has decremented the count, waiting must succeed immediately.
+func TestUnlinkAfterCloseNotBusy(t *testing.T) {
+	tr := newOpenTracker()
+	tr.inc("f")
+	tr.dec("f")
+	start := time.Now()
+	if !tr.waitIdle("f", time.Second) {
+		t.Fatal("waitIdle reported busy for a closed file")
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("waitIdle on an idle path blocked instead of returning")
+	}
+}
+
+// TestWaitIdleWakesOnRelease checks that a waiter is woken by the release
+// itself rather than only by the timeout.
+func TestWaitIdleWakesOnRelease(t *testing.T) {
+	tr := newOpenTracker()
+	tr.inc("f")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		tr.dec("f")
+	}()
+	start := time.Now()
+	if !tr.waitIdle("f", 5*time.Second) {
+		t.Fatal("waitIdle timed out despite the release")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("waitIdle took %v, should wake on the release signal", time.Since(start))
+	}
+}
+
+func TestWaitIdleTimesOutWhileOpen(t *testing.T) {
+	tr := newOpenTracker()
+	tr.inc("f")
+	if tr.waitIdle("f", 20*time.Millisecond) {
+		t.Fatal("waitIdle reported idle while a handle is open")
+	}
+}
+
+func TestRenameMovesOpenCount(t *testing.T) {
+	tr := newOpenTracker()
+	tr.inc("old")
+	tr.rename("old", "new")
+	if tr.waitIdle("new", 10*time.Millisecond) {
+		t.Fatal("open count did not follow the rename")
+	}
+	if !tr.waitIdle("old", 10*time.Millisecond) {
+		t.Fatal("old path still counted as open after rename")
+	}
+	tr.dec("new")
+	if !tr.waitIdle("new", time.Second) {
+		t.Fatal("new path still busy after release")
+	}
+}
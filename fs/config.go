@@ -0,0 +1,21 @@
+package fs
+
+// ConfigInfo carries the transfer tuning shared by the accounting readers
+// wrapped around object streams.
+type ConfigInfo struct {
+	// BufferSize is the amount of read-ahead buffered per transfer. It is
+	// split into asyncreader-sized buffers; below one buffer no read-ahead
+	// is done.
+	BufferSize SizeSuffix
+	// BwLimit caps read bandwidth in bytes per second. Zero or negative
+	// means unlimited.
+	BwLimit SizeSuffix
+}
+
+// DefaultConfig returns the transfer configuration used when the caller
+// doesn't supply one.
+func DefaultConfig() *ConfigInfo {
+	return &ConfigInfo{
+		BufferSize: 16 * Mebi,
+	}
+}
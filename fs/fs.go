@@ -0,0 +1,10 @@
+package fs
+
+import "os"
+
+// FileExists reports whether the local path exists. It doesn't distinguish
+// files from directories; callers who care should stat themselves.
+func FileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
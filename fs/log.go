@@ -0,0 +1,90 @@
+package fs
+
+import (
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogLevel selects how chatty the package-level logging helpers are.
+// Messages above the configured level are dropped before formatting.
+type LogLevel int32
+
+const (
+	// LogLevelOff silences all per-operation logging.
+	LogLevelOff LogLevel = iota
+	LogLevelError
+	LogLevelWarn
+	LogLevelInfo
+	LogLevelDebug
+)
+
+// logLevel is the current threshold, adjusted with SetLogLevel. Reads
+// and writes are atomic so hot paths can check it without a lock.
+var logLevel = int32(LogLevelInfo)
+
+// SetLogLevel sets the threshold for Debugf, Infof, Warnf and Errorf.
+// The underlying logrus level is raised to match, so debug messages
+// actually print when asked for.
+func SetLogLevel(level LogLevel) {
+	atomic.StoreInt32(&logLevel, int32(level))
+	switch level {
+	case LogLevelDebug:
+		logrus.SetLevel(logrus.DebugLevel)
+	case LogLevelInfo:
+		logrus.SetLevel(logrus.InfoLevel)
+	case LogLevelWarn:
+		logrus.SetLevel(logrus.WarnLevel)
+	case LogLevelError, LogLevelOff:
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+}
+
+// ParseLogLevel maps the config spellings to a LogLevel. Unknown names
+// fall back to info so a typo never silences errors.
+func ParseLogLevel(s string) LogLevel {
+	switch s {
+	case "off", "none":
+		return LogLevelOff
+	case "error":
+		return LogLevelError
+	case "warn", "warning":
+		return LogLevelWarn
+	case "debug":
+		return LogLevelDebug
+	}
+	return LogLevelInfo
+}
+
+func enabled(level LogLevel) bool {
+	return LogLevel(atomic.LoadInt32(&logLevel)) >= level
+}
+
+// Debugf logs expected, high-volume events: cache misses, probing
+// lookups for keys that may not exist, retries.
+func Debugf(format string, args ...interface{}) {
+	if enabled(LogLevelDebug) {
+		logrus.Debugf(format, args...)
+	}
+}
+
+// Infof logs noteworthy but healthy events.
+func Infof(format string, args ...interface{}) {
+	if enabled(LogLevelInfo) {
+		logrus.Infof(format, args...)
+	}
+}
+
+// Warnf logs degraded-but-continuing conditions.
+func Warnf(format string, args ...interface{}) {
+	if enabled(LogLevelWarn) {
+		logrus.Warnf(format, args...)
+	}
+}
+
+// Errorf logs operation failures that are surfaced to the caller too.
+func Errorf(format string, args ...interface{}) {
+	if enabled(LogLevelError) {
+		logrus.Errorf(format, args...)
+	}
+}
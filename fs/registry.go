@@ -0,0 +1,100 @@
+package fs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// RegInfo describes one registered backend: its lookup name and how to
+// build its mount root from a target and a connection string.
+type RegInfo struct {
+	// Name is the type prefix callers use to select the backend, e.g.
+	// "s3" in "s3:bucket/path".
+	Name string
+	// Description is a one-line human-readable summary.
+	Description string
+	// NewFs builds the backend's root node from a target (for s3 a
+	// bucket/prefix, empty for all buckets) and a connection string.
+	// The result is the backend's FUSE root; it is typed loosely so
+	// this package carries no FUSE dependency.
+	NewFs func(name, config string) (interface{}, error)
+	// Options describes the backend's configurable fields, in the order
+	// an interactive setup should ask for them. Each Name is a key the
+	// backend's connection-string parser understands.
+	Options []Option
+}
+
+// Option describes one configurable field of a backend.
+type Option struct {
+	// Name is the connection-string key, e.g. "url".
+	Name string
+	// Help is shown alongside the prompt.
+	Help string
+	// Default is the value used when the answer is left empty, already
+	// in its string spelling.
+	Default string
+	// Examples suggest common values without being exhaustive.
+	Examples []OptionExample
+	// Required marks fields setup must not leave empty.
+	Required bool
+	// IsPassword marks secrets, so prompts can mask the input and
+	// listings can redact the value.
+	IsPassword bool
+}
+
+// OptionExample is one suggested value for an Option.
+type OptionExample struct {
+	Value string
+	Help  string
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*RegInfo{}
+)
+
+// Register adds a backend to the registry. Backends call it from init,
+// so a duplicate name is a programming error and panics.
+func Register(info *RegInfo) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[info.Name]; dup {
+		panic(fmt.Sprintf("backend %q registered twice", info.Name))
+	}
+	registry[info.Name] = info
+}
+
+// Find returns the backend registered under name.
+func Find(name string) (*RegInfo, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	info, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	return info, nil
+}
+
+// MustFind is Find for backends that are compiled in by construction;
+// a miss is a programming error and panics.
+func MustFind(name string) *RegInfo {
+	info, err := Find(name)
+	if err != nil {
+		panic(err)
+	}
+	return info
+}
+
+// Backends returns the registered backend names, sorted, for help text
+// and configuration prompts.
+func Backends() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,80 @@
+// Package fs provides small shared infrastructure for the module: size
+// parsing, logging and generic file helpers.
+package fs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SizeSuffix is a byte count with a human readable form such as 10M or
+// 1.5G. Multiples are binary: K is 1024, M is 1024*1024 and so on.
+type SizeSuffix int64
+
+const (
+	// SizeSuffixBase is the unit of SizeSuffix.
+	SizeSuffixBase SizeSuffix = 1
+	Kibi                      = 1024 * SizeSuffixBase
+	Mebi                      = 1024 * Kibi
+	Gibi                      = 1024 * Mebi
+	Tebi                      = 1024 * Gibi
+)
+
+// String returns the size with the largest suffix that divides it cleanly.
+func (x SizeSuffix) String() string {
+	switch {
+	case x < 0:
+		return "off"
+	case x == 0:
+		return "0"
+	}
+	for _, unit := range []struct {
+		mult   SizeSuffix
+		suffix string
+	}{{Tebi, "T"}, {Gibi, "G"}, {Mebi, "M"}, {Kibi, "K"}} {
+		if x >= unit.mult && x%unit.mult == 0 {
+			return strconv.FormatInt(int64(x/unit.mult), 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(x), 10)
+}
+
+// Set parses s into x. An optional single letter suffix (b, K, M, G, T, in
+// either case) scales the value; "off" means unlimited and parses to -1.
+func (x *SizeSuffix) Set(s string) error {
+	if s == "" {
+		return fmt.Errorf("empty size")
+	}
+	if strings.EqualFold(s, "off") {
+		*x = -1
+		return nil
+	}
+	mult := SizeSuffixBase
+	switch s[len(s)-1] {
+	case 'b', 'B':
+		s = s[:len(s)-1]
+	case 'k', 'K':
+		mult, s = Kibi, s[:len(s)-1]
+	case 'm', 'M':
+		mult, s = Mebi, s[:len(s)-1]
+	case 'g', 'G':
+		mult, s = Gibi, s[:len(s)-1]
+	case 't', 'T':
+		mult, s = Tebi, s[:len(s)-1]
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("bad size %q: %w", s, err)
+	}
+	if value < 0 {
+		return fmt.Errorf("size can't be negative: %q", s)
+	}
+	*x = SizeSuffix(value * float64(mult))
+	return nil
+}
+
+// Type implements pflag-style Value naming.
+func (x SizeSuffix) Type() string {
+	return "SizeSuffix"
+}
@@ -0,0 +1,134 @@
+// Package asyncreader provides an io.ReadCloser that reads ahead of its
+// consumer into a fixed number of background buffers, smoothing out the
+// latency of ranged object downloads.
+package asyncreader
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// BufferSize is the size of each read-ahead buffer.
+const BufferSize = 1024 * 1024
+
+// buffer is one filled read-ahead chunk. err, if set, applies after the
+// data has been consumed.
+type buffer struct {
+	data []byte
+	err  error
+}
+
+// AsyncReader reads from an underlying reader in a background goroutine,
+// keeping up to the configured number of buffers filled ahead of Read.
+type AsyncReader struct {
+	in    io.ReadCloser
+	ready chan *buffer
+	token chan struct{}
+	stop  chan struct{}
+
+	stopOnce sync.Once
+
+	mu  sync.Mutex
+	cur *buffer
+	pos int
+}
+
+// New starts read-ahead over rd with the given number of buffers.
+func New(rd io.ReadCloser, buffers int) (*AsyncReader, error) {
+	if rd == nil {
+		return nil, errors.New("nil reader supplied")
+	}
+	if buffers <= 0 {
+		return nil, errors.New("number of buffers too small")
+	}
+	a := &AsyncReader{
+		in:    rd,
+		ready: make(chan *buffer, buffers),
+		token: make(chan struct{}, buffers),
+		stop:  make(chan struct{}),
+	}
+	for i := 0; i < buffers; i++ {
+		a.token <- struct{}{}
+	}
+	go a.fill()
+	return a, nil
+}
+
+// fill keeps the ready queue supplied until the stream ends or buffering
+// is stopped.
+func (a *AsyncReader) fill() {
+	defer close(a.ready)
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-a.token:
+		}
+		b := &buffer{data: make([]byte, BufferSize)}
+		n, err := io.ReadFull(a.in, b.data)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		b.data = b.data[:n]
+		b.err = err
+		select {
+		case a.ready <- b:
+		case <-a.stop:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (a *AsyncReader) Read(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for {
+		if a.cur != nil {
+			if a.pos < len(a.cur.data) {
+				n := copy(p, a.cur.data[a.pos:])
+				a.pos += n
+				return n, nil
+			}
+			if a.cur.err != nil {
+				return 0, a.cur.err
+			}
+			// Recycle the slot unless buffering has stopped.
+			select {
+			case a.token <- struct{}{}:
+			default:
+			}
+			a.cur = nil
+		}
+		b, ok := <-a.ready
+		if !ok {
+			return 0, io.EOF
+		}
+		a.cur, a.pos = b, 0
+	}
+}
+
+// StopBuffering halts the background reads but keeps already-buffered data
+// readable.
+func (a *AsyncReader) StopBuffering() {
+	a.stopOnce.Do(func() { close(a.stop) })
+}
+
+// Abandon stops buffering and discards anything buffered. The underlying
+// reader is closed.
+func (a *AsyncReader) Abandon() {
+	a.StopBuffering()
+	for range a.ready {
+		// Drain so fill can't be blocked on send.
+	}
+	a.in.Close()
+}
+
+// Close abandons the read-ahead and closes the underlying reader.
+func (a *AsyncReader) Close() error {
+	a.Abandon()
+	return nil
+}
@@ -0,0 +1,117 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file enforces the sharing model client-side: before an object
+// operation goes out, the caller is checked against the bucket's policy.
+// The backend still has the final say — this is a fast local gate that
+// turns a doomed request into ErrorPermissionDenied without a round-trip.
+
+// Actions evaluated by canAccess.
+const (
+	actionRead   = "read"
+	actionWrite  = "write"
+	actionDelete = "delete"
+)
+
+// policyCacheTTL is how long a fetched bucket policy is trusted before
+// re-reading it from the backend.
+const policyCacheTTL = 30 * time.Second
+
+// policyCache memoizes bucket policies for canAccess, including the
+// "no policy" answer, so per-operation checks don't turn into
+// per-operation GetBucketPolicy calls.
+type policyCache struct {
+	mu      sync.Mutex
+	entries map[string]policyCacheEntry
+}
+
+type policyCacheEntry struct {
+	policy  *BucketPolicy
+	fetched time.Time
+}
+
+// cachedBucketPolicy returns the bucket's policy, from cache when fresh.
+func (c *s3Client) cachedBucketPolicy(ctx context.Context, bucket string) (*BucketPolicy, error) {
+	c.policies.mu.Lock()
+	if e, ok := c.policies.entries[bucket]; ok && time.Since(e.fetched) < policyCacheTTL {
+		c.policies.mu.Unlock()
+		return e.policy, nil
+	}
+	c.policies.mu.Unlock()
+	policy, err := c.GetBucketPolicy(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	c.policies.mu.Lock()
+	if c.policies.entries == nil {
+		c.policies.entries = make(map[string]policyCacheEntry)
+	}
+	c.policies.entries[bucket] = policyCacheEntry{policy: policy, fetched: time.Now()}
+	c.policies.mu.Unlock()
+	return policy, nil
+}
+
+// invalidatePolicy drops the cached policy after a policy write so the
+// next access check sees the new grants.
+func (c *s3Client) invalidatePolicy(bucket string) {
+	c.policies.mu.Lock()
+	delete(c.policies.entries, bucket)
+	c.policies.mu.Unlock()
+}
+
+// permAllows reports whether a permission level grants action.
+func permAllows(perm Perm, action string) bool {
+	switch perm {
+	case PermRW, PermAdmin:
+		return true
+	case PermRO:
+		return action == actionRead
+	case PermWO:
+		return action == actionWrite
+	}
+	return false
+}
+
+// canAccess evaluates the bucket's policy for user against key. The
+// owner may do anything; shared principals are bounded by their
+// permission level, bucket-wide or for the directory holding key. A
+// bucket without a policy, an anonymous caller or a failed policy fetch
+// all allow the attempt — the backend enforces the real rules, and
+// failing open keeps a policy outage from blocking every read.
+func (c *s3Client) canAccess(ctx context.Context, user, bucket, key, action string) bool {
+	if user == "" {
+		return true
+	}
+	policy, err := c.cachedBucketPolicy(ctx, bucket)
+	if err != nil || policy == nil {
+		return true
+	}
+	if policy.owner == user {
+		return true
+	}
+	for _, s := range policy.shares {
+		if s.user == user {
+			return permAllows(s.perm, action)
+		}
+	}
+	for dir, dp := range policy.dirs {
+		if !strings.HasPrefix(key, dir+"/") {
+			continue
+		}
+		if dp.owner == user {
+			return true
+		}
+		for _, s := range dp.shares {
+			if s.user == user {
+				return permAllows(s.perm, action)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCanAccessGatesObjectOps(t *testing.T) {
+	cli := fakeClient(t, "gated")
+	ctx := context.Background()
+	if err := cli.PutObject(ctx, "alice", "gated", "doc", []byte("body")); err != nil {
+		t.Fatalf("PutObject as alice: %v", err)
+	}
+	policy := GenerateBucketPolicy("gated", "alice", map[string]Perm{"bob": PermRO})
+	if err := cli.PutBucketPolicy(ctx, "gated", policy); err != nil {
+		t.Fatalf("PutBucketPolicy: %v", err)
+	}
+
+	// The owner keeps full access.
+	if err := cli.PutObject(ctx, "alice", "gated", "doc", []byte("v2")); err != nil {
+		t.Fatalf("owner write: %v", err)
+	}
+
+	// A read-only share may read but neither write nor delete.
+	if _, err := cli.GetObject(ctx, "bob", "gated", "doc"); err != nil {
+		t.Fatalf("bob read: %v", err)
+	}
+	if err := cli.PutObject(ctx, "bob", "gated", "doc", []byte("x")); !errors.Is(err, ErrorPermissionDenied) {
+		t.Fatalf("bob write = %v, want ErrorPermissionDenied", err)
+	}
+	if err := cli.DeleteObject(ctx, "bob", "gated", "doc"); !errors.Is(err, ErrorPermissionDenied) {
+		t.Fatalf("bob delete = %v, want ErrorPermissionDenied", err)
+	}
+
+	// A user with no grant at all is denied outright.
+	if _, err := cli.GetObject(ctx, "carol", "gated", "doc"); !errors.Is(err, ErrorPermissionDenied) {
+		t.Fatalf("carol read = %v, want ErrorPermissionDenied", err)
+	}
+}
+
+func TestCanAccessWithoutPolicyAllows(t *testing.T) {
+	cli := fakeClient(t, "open")
+	ctx := context.Background()
+	if err := cli.PutObject(ctx, "anyone", "open", "k", []byte("v")); err != nil {
+		t.Fatalf("PutObject without policy: %v", err)
+	}
+	if _, err := cli.GetObject(ctx, "someone-else", "open", "k"); err != nil {
+		t.Fatalf("GetObject without policy: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package s3
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// validACL checks opt.ACL against the canned object ACLs the protocol
+// defines. Empty is fine — it means "don't send one". Validating here,
+// at client construction, beats shipping a typo to the backend and
+// getting InvalidArgument on every upload.
+func validACL(acl string) error {
+	if acl == "" {
+		return nil
+	}
+	for _, known := range types.ObjectCannedACLPrivate.Values() {
+		if types.ObjectCannedACL(acl) == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown canned ACL %q", acl)
+}
+
+// bucketACL narrows the configured ACL to what buckets accept: the
+// object-only grants (aws-exec-read, bucket-owner-*) have no bucket
+// equivalent and are dropped rather than sent to fail.
+func (c *s3Client) bucketACL() types.BucketCannedACL {
+	for _, known := range types.BucketCannedACLPrivate.Values() {
+		if types.BucketCannedACL(c.opt.ACL) == known {
+			return known
+		}
+	}
+	return ""
+}
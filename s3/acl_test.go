@@ -0,0 +1,48 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/ThierryZhou/go-s3fs/s3/s3test"
+)
+
+func TestValidACL(t *testing.T) {
+	for _, acl := range []string{"", "private", "public-read", "public-read-write", "bucket-owner-full-control"} {
+		if err := validACL(acl); err != nil {
+			t.Errorf("validACL(%q): %v", acl, err)
+		}
+	}
+	for _, acl := range []string{"public", "Private", "world-readable"} {
+		if err := validACL(acl); err == nil {
+			t.Errorf("validACL(%q) succeeded, want error", acl)
+		}
+	}
+}
+
+func TestClientRejectsUnknownACL(t *testing.T) {
+	opt := defaultOption
+	opt.ACL = "world-readable"
+	if _, err := NewS3ClientWithAPI(opt, s3test.New()); err == nil {
+		t.Fatal("NewS3ClientWithAPI with bogus ACL succeeded, want error")
+	}
+}
+
+func TestBucketACLNarrowing(t *testing.T) {
+	cases := []struct{ acl, want string }{
+		{"public-read", "public-read"},
+		// Object-only grants have no bucket equivalent.
+		{"bucket-owner-full-control", ""},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		opt := defaultOption
+		opt.ACL = tc.acl
+		cli, err := NewS3ClientWithAPI(opt, s3test.New())
+		if err != nil {
+			t.Fatalf("NewS3ClientWithAPI(acl=%q): %v", tc.acl, err)
+		}
+		if got := string(cli.bucketACL()); got != tc.want {
+			t.Errorf("bucketACL() with %q = %q, want %q", tc.acl, got, tc.want)
+		}
+	}
+}
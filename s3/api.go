@@ -0,0 +1,48 @@
+package s3
+
+import (
+	"context"
+
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3API is the subset of *s3v2.Client this package calls. It exists so
+// tests can substitute an in-memory implementation (see the s3test
+// package) for the real backend. It is a superset of the transfer
+// manager's UploadAPIClient and DownloadAPIClient interfaces, so the
+// uploader and downloader work against it unchanged.
+type S3API interface {
+	PutObject(ctx context.Context, in *s3v2.PutObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.PutObjectOutput, error)
+	GetObject(ctx context.Context, in *s3v2.GetObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.GetObjectOutput, error)
+	HeadObject(ctx context.Context, in *s3v2.HeadObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.HeadObjectOutput, error)
+	CopyObject(ctx context.Context, in *s3v2.CopyObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.CopyObjectOutput, error)
+	DeleteObject(ctx context.Context, in *s3v2.DeleteObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, in *s3v2.DeleteObjectsInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteObjectsOutput, error)
+	ListObjectsV2(ctx context.Context, in *s3v2.ListObjectsV2Input, optFns ...func(*s3v2.Options)) (*s3v2.ListObjectsV2Output, error)
+	ListObjectVersions(ctx context.Context, in *s3v2.ListObjectVersionsInput, optFns ...func(*s3v2.Options)) (*s3v2.ListObjectVersionsOutput, error)
+	RestoreObject(ctx context.Context, in *s3v2.RestoreObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.RestoreObjectOutput, error)
+
+	ListBuckets(ctx context.Context, in *s3v2.ListBucketsInput, optFns ...func(*s3v2.Options)) (*s3v2.ListBucketsOutput, error)
+	CreateBucket(ctx context.Context, in *s3v2.CreateBucketInput, optFns ...func(*s3v2.Options)) (*s3v2.CreateBucketOutput, error)
+	DeleteBucket(ctx context.Context, in *s3v2.DeleteBucketInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteBucketOutput, error)
+	HeadBucket(ctx context.Context, in *s3v2.HeadBucketInput, optFns ...func(*s3v2.Options)) (*s3v2.HeadBucketOutput, error)
+	PutBucketPolicy(ctx context.Context, in *s3v2.PutBucketPolicyInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketPolicyOutput, error)
+	GetBucketPolicy(ctx context.Context, in *s3v2.GetBucketPolicyInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketPolicyOutput, error)
+	DeleteBucketPolicy(ctx context.Context, in *s3v2.DeleteBucketPolicyInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteBucketPolicyOutput, error)
+	PutBucketVersioning(ctx context.Context, in *s3v2.PutBucketVersioningInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketVersioningOutput, error)
+	GetBucketVersioning(ctx context.Context, in *s3v2.GetBucketVersioningInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketVersioningOutput, error)
+	PutBucketCors(ctx context.Context, in *s3v2.PutBucketCorsInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketCorsOutput, error)
+	GetBucketCors(ctx context.Context, in *s3v2.GetBucketCorsInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketCorsOutput, error)
+	PutBucketTagging(ctx context.Context, in *s3v2.PutBucketTaggingInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketTaggingOutput, error)
+	GetBucketTagging(ctx context.Context, in *s3v2.GetBucketTaggingInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketTaggingOutput, error)
+	PutBucketLifecycleConfiguration(ctx context.Context, in *s3v2.PutBucketLifecycleConfigurationInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketLifecycleConfigurationOutput, error)
+	GetBucketLifecycleConfiguration(ctx context.Context, in *s3v2.GetBucketLifecycleConfigurationInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketLifecycleConfigurationOutput, error)
+
+	CreateMultipartUpload(ctx context.Context, in *s3v2.CreateMultipartUploadInput, optFns ...func(*s3v2.Options)) (*s3v2.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, in *s3v2.UploadPartInput, optFns ...func(*s3v2.Options)) (*s3v2.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, in *s3v2.CompleteMultipartUploadInput, optFns ...func(*s3v2.Options)) (*s3v2.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, in *s3v2.AbortMultipartUploadInput, optFns ...func(*s3v2.Options)) (*s3v2.AbortMultipartUploadOutput, error)
+	ListMultipartUploads(ctx context.Context, in *s3v2.ListMultipartUploadsInput, optFns ...func(*s3v2.Options)) (*s3v2.ListMultipartUploadsOutput, error)
+}
+
+var _ S3API = (*s3v2.Client)(nil)
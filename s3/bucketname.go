@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrInvalidBucketName is returned when a bucket name violates the S3
+// naming rules. The wrapped error carries the specific reason.
+var ErrInvalidBucketName = errors.New("invalid bucket name")
+
+// ValidBucketName checks name against the S3 bucket naming rules:
+// 3-63 characters of lowercase letters, digits, dots and hyphens,
+// beginning and ending with a letter or digit, with no consecutive
+// dots, no dot next to a hyphen, and not formatted like an IP address.
+// It returns nil for a valid name and an error wrapping
+// ErrInvalidBucketName otherwise.
+func ValidBucketName(name string) error {
+	if len(name) < 3 || len(name) > 63 {
+		return fmt.Errorf("%w: %q must be 3 to 63 characters long", ErrInvalidBucketName, name)
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '.', c == '-':
+		default:
+			return fmt.Errorf("%w: %q contains invalid character %q", ErrInvalidBucketName, name, c)
+		}
+	}
+	if !isAlnum(name[0]) || !isAlnum(name[len(name)-1]) {
+		return fmt.Errorf("%w: %q must begin and end with a letter or digit", ErrInvalidBucketName, name)
+	}
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("%w: %q contains consecutive dots", ErrInvalidBucketName, name)
+	}
+	if strings.Contains(name, ".-") || strings.Contains(name, "-.") {
+		return fmt.Errorf("%w: %q has a dot adjacent to a hyphen", ErrInvalidBucketName, name)
+	}
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("%w: %q must not be formatted as an IP address", ErrInvalidBucketName, name)
+	}
+	return nil
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
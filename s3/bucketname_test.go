@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidBucketName(t *testing.T) {
+	longName := make([]byte, 64)
+	for i := range longName {
+		longName[i] = 'a'
+	}
+	cases := []struct {
+		name string
+		ok   bool
+	}{
+		{"docs", true},
+		{"my-bucket", true},
+		{"my.bucket.2024", true},
+		{"abc", true},
+		{"0-9", true},
+
+		{"ab", false},             // too short
+		{string(longName), false}, // too long
+		{"MyBucket", false},       // uppercase
+		{"my_bucket", false},      // underscore
+		{"-bucket", false},        // leading hyphen
+		{"bucket-", false},        // trailing hyphen
+		{".bucket", false},        // leading dot
+		{"my..bucket", false},     // consecutive dots
+		{"my.-bucket", false},     // dot next to hyphen
+		{"192.168.1.1", false},    // IP address
+		{"bucket name", false},    // space
+		{"bücket", false},         // non-ASCII
+	}
+	for _, tc := range cases {
+		err := ValidBucketName(tc.name)
+		if tc.ok && err != nil {
+			t.Errorf("ValidBucketName(%q) = %v, want nil", tc.name, err)
+		}
+		if !tc.ok {
+			if err == nil {
+				t.Errorf("ValidBucketName(%q) = nil, want error", tc.name)
+			} else if !errors.Is(err, ErrInvalidBucketName) {
+				t.Errorf("ValidBucketName(%q) = %v, not ErrInvalidBucketName", tc.name, err)
+			}
+		}
+	}
+}
+
+func TestCreateBucketRejectsInvalidName(t *testing.T) {
+	cli := fakeClient(t, "named")
+	if _, err := cli.CreateBucket(context.Background(), "alice", "Bad_Name"); !errors.Is(err, ErrInvalidBucketName) {
+		t.Fatalf("CreateBucket(Bad_Name) = %v, want ErrInvalidBucketName", err)
+	}
+}
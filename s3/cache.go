@@ -0,0 +1,194 @@
+package s3
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ThierryZhou/go-s3fs/fs"
+)
+
+// diskCache is a read-through cache of object bodies on local disk. Entries
+// are keyed by bucket, key and ETag, so a changed object can never serve
+// stale bytes: a new ETag simply misses and the old entry ages out via LRU
+// eviction against the configured size budget.
+type diskCache struct {
+	dir     string
+	maxSize int64
+
+	mu    sync.Mutex
+	lru   *list.List // of *cacheEntry, most recently used in front
+	index map[string]*list.Element
+	// etags remembers the last stored ETag per bucket/key so reads can
+	// revalidate with a conditional GET instead of a Head plus full
+	// refetch.
+	etags map[string]string
+	size  int64
+	hits  uint64
+	miss  uint64
+}
+
+type cacheEntry struct {
+	name string
+	size int64
+}
+
+func newDiskCache(dir string, maxSize int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	return &diskCache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		index:   make(map[string]*list.Element),
+		etags:   make(map[string]string),
+	}, nil
+}
+
+// entryName hashes the identity of one object version into a filename.
+func entryName(bucket, key, etag string) string {
+	sum := sha256.Sum256([]byte(bucket + "\x00" + key + "\x00" + etag))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached body for this object version, if present.
+func (dc *diskCache) get(bucket, key, etag string) ([]byte, bool) {
+	name := entryName(bucket, key, etag)
+	dc.mu.Lock()
+	el, ok := dc.index[name]
+	if !ok {
+		dc.miss++
+		counters.cacheMisses.Add(1)
+		dc.mu.Unlock()
+		return nil, false
+	}
+	dc.lru.MoveToFront(el)
+	dc.hits++
+	counters.cacheHits.Add(1)
+	dc.mu.Unlock()
+	data, err := os.ReadFile(filepath.Join(dc.dir, name))
+	if err != nil {
+		// The file went away underneath us; treat as a miss.
+		dc.remove(name)
+		return nil, false
+	}
+	return data, true
+}
+
+// put stores the body for this object version and evicts the least
+// recently used entries once the size budget is exceeded.
+func (dc *diskCache) put(bucket, key, etag string, data []byte) {
+	if dc.maxSize > 0 && int64(len(data)) > dc.maxSize {
+		return
+	}
+	name := entryName(bucket, key, etag)
+	if err := os.WriteFile(filepath.Join(dc.dir, name), data, 0600); err != nil {
+		fs.Errorf("cache write %s error: %v", name, err)
+		return
+	}
+	dc.mu.Lock()
+	dc.etags[bucket+"\x00"+key] = etag
+	if el, ok := dc.index[name]; ok {
+		dc.lru.MoveToFront(el)
+		dc.mu.Unlock()
+		return
+	}
+	dc.index[name] = dc.lru.PushFront(&cacheEntry{name: name, size: int64(len(data))})
+	dc.size += int64(len(data))
+	var evict []string
+	for dc.maxSize > 0 && dc.size > dc.maxSize {
+		el := dc.lru.Back()
+		if el == nil {
+			break
+		}
+		e := dc.lru.Remove(el).(*cacheEntry)
+		delete(dc.index, e.name)
+		dc.size -= e.size
+		evict = append(evict, e.name)
+	}
+	dc.mu.Unlock()
+	for _, name := range evict {
+		os.Remove(filepath.Join(dc.dir, name))
+	}
+}
+
+// etag returns the last ETag stored for bucket/key, if any.
+func (dc *diskCache) etag(bucket, key string) (string, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	etag, ok := dc.etags[bucket+"\x00"+key]
+	return etag, ok
+}
+
+// remove drops one entry from the index and disk.
+func (dc *diskCache) remove(name string) {
+	dc.mu.Lock()
+	if el, ok := dc.index[name]; ok {
+		e := dc.lru.Remove(el).(*cacheEntry)
+		delete(dc.index, e.name)
+		dc.size -= e.size
+	}
+	dc.mu.Unlock()
+	os.Remove(filepath.Join(dc.dir, name))
+}
+
+// stats returns the hit/miss counters for diagnostics.
+func (dc *diskCache) stats() (hits, misses uint64) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.hits, dc.miss
+}
+
+// getObjectCached is GetObject routed through the disk cache when one is
+// configured. A cached copy is revalidated with a conditional GET on its
+// ETag — one cheap round-trip when unchanged, the fresh body otherwise.
+// Without a usable cached copy the read falls back to Head plus full
+// download as before.
+func (c *s3Client) getObjectCached(ctx context.Context, userID, bucket, path string) ([]byte, error) {
+	if c.cache == nil {
+		return c.GetObject(ctx, userID, bucket, path)
+	}
+	if etag, ok := c.cache.etag(bucket, path); ok {
+		if data, ok := c.cache.get(bucket, path, etag); ok {
+			fresh, changed, err := c.GetObjectIfChanged(ctx, userID, bucket, path, etag)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				return data, nil
+			}
+			if head, err := c.HeadObject(ctx, userID, bucket, path); err == nil {
+				c.cache.put(bucket, path, head.ETag, fresh)
+			}
+			return fresh, nil
+		}
+	}
+	head, err := c.HeadObject(ctx, userID, bucket, path)
+	if err != nil {
+		return nil, err
+	}
+	if data, ok := c.cache.get(bucket, path, head.ETag); ok {
+		return data, nil
+	}
+	data, err := c.GetObject(ctx, userID, bucket, path)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(bucket, path, head.ETag, data)
+	return data, nil
+}
+
+// CacheStats reports read cache hits and misses, both zero when no cache is
+// configured.
+func (c *s3Client) CacheStats() (hits, misses uint64) {
+	if c.cache == nil {
+		return 0, 0
+	}
+	return c.cache.stats()
+}
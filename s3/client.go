@@ -0,0 +1,1195 @@
+// Package s3 implements a client for S3-compatible object stores and the
+// FUSE filesystem mounted on top of it.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/ThierryZhou/go-s3fs/fs"
+)
+
+const (
+	// MAX_GOROUTES bounds the number of concurrent S3 calls issued by bulk
+	// operations such as DeleteBucket.
+	MAX_GOROUTES = 32
+
+	// maxRetryAttempts and maxRetryBackoff configure the standard retryer.
+	maxRetryAttempts = 20
+	maxRetryBackoff  = 5 * time.Minute
+
+	// presignExpiry is how long generated download links stay valid.
+	presignExpiry = 15 * time.Minute
+
+	presignCacheSize = 1024
+)
+
+// Object describes a single S3 object, or a common prefix when IsDir is set.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	IsDir        bool
+	// Metadata holds the object's user metadata (without the x-amz-meta-
+	// prefix). Only filled by HeadObject; listings don't carry it.
+	Metadata map[string]string
+	// StorageClass is the object's storage class as reported by
+	// HeadObject; empty means the backend default (STANDARD).
+	StorageClass string
+}
+
+// Bucket describes a bucket.
+type Bucket struct {
+	Name string
+	// Region is where the bucket lives; on creation it is the client's
+	// configured region, on listing it is not populated (ListBuckets does
+	// not report it).
+	Region    string
+	CreatedAt time.Time
+}
+
+// s3Client wraps the aws-sdk-go-v2 S3 client with the conventions used by
+// the rest of this module: flat bucket/path arguments, transfer managers for
+// large objects and an LRU cache for presigned links.
+type s3Client struct {
+	opt          Option
+	cli          S3API
+	uploader     *manager.Uploader
+	downloader   *manager.Downloader
+	presigner    *s3v2.PresignClient
+	presignCache *lru.Cache
+	// cache is the optional local disk read cache, nil when disabled.
+	cache *diskCache
+	// wb is the optional asynchronous upload queue, nil when disabled.
+	wb *writeback
+	// ci is the transfer configuration applied to streaming reads.
+	ci *fs.ConfigInfo
+	// log receives the client's diagnostics; see Option.Logger.
+	log Logger
+	// httpClient is the transport used for backend calls, kept so Close
+	// can drop idle connections. Nil when the SDK's default is in use.
+	httpClient httpDoer
+	// policies caches bucket policies for the client-side access gate.
+	policies policyCache
+}
+
+// Close releases the client's resources: it drains the write-back queue
+// so no staged upload is lost, empties the presign cache and closes any
+// idle backend connections. The client must not be used afterwards.
+func (c *s3Client) Close() error {
+	var err error
+	if c.wb != nil {
+		err = c.wb.close()
+	}
+	if c.presignCache != nil {
+		c.presignCache.Purge()
+	}
+	if closer, ok := c.httpClient.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+	return err
+}
+
+// NewS3Client connects to the endpoint described by opt. Empty fields
+// are defaulted and clearly-wrong ones rejected up front, so a mistyped
+// connection string fails here rather than on the first request.
+func NewS3Client(opt Option) (*s3Client, error) {
+	opt = *opt.WithDefaults()
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	if opt.LogLevel != "" {
+		fs.SetLogLevel(fs.ParseLogLevel(opt.LogLevel))
+	}
+	log := opt.Logger
+	if log == nil {
+		log = fsLogger{}
+	}
+	skew := &clockSkew{}
+	loadOpts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(opt.Region),
+		awsconfig.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = maxRetryAttempts
+				o.Backoff = NewExponentialJitterBackoff(maxRetryBackoff)
+				// Checked first: a definitive answer short-circuits the
+				// SDK's own retryables. The skew check must beat the 4xx
+				// cutoff or RequestTimeTooSkewed would be permanent.
+				o.Retryables = append([]retry.IsErrorRetryable{skewRetry{skew: skew, log: log}, noRetry4xx{}}, o.Retryables...)
+			})
+		}),
+	}
+	var httpClient httpDoer
+	// The buildable client keeps the SDK's transport defaults (TLS,
+	// dial timeouts) and layers the configured proxy and pool tuning on
+	// top. It also backs the health-checking client below, so the
+	// tweaks apply no matter how many endpoints are in play.
+	buildable := awshttp.NewBuildableClient()
+	if tweaks := opt.transportTweaks(); tweaks != nil {
+		buildable = buildable.WithTransportOptions(tweaks)
+		httpClient = buildable
+		loadOpts = append(loadOpts, awsconfig.WithHTTPClient(buildable))
+	}
+	// Inline keys beat every other credential source; otherwise the
+	// requested source narrows the SDK's default chain, which already
+	// consults the environment and the shared files in that order.
+	switch {
+	case opt.AccessKey != "" || opt.SecretKey != "":
+		loadOpts = append(loadOpts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(opt.AccessKey, opt.SecretKey, "")))
+	case opt.Anonymous:
+		// Public buckets: send unsigned requests and skip the default
+		// chain entirely, so a mount needs no keys at all.
+		loadOpts = append(loadOpts, awsconfig.WithCredentialsProvider(aws.AnonymousCredentials{}))
+	case opt.CredentialsSource == "env":
+		creds, err := awsconfig.NewEnvConfig()
+		if err != nil {
+			return nil, fmt.Errorf("read credentials from environment: %w", err)
+		}
+		loadOpts = append(loadOpts, awsconfig.WithCredentialsProvider(
+			credentials.StaticCredentialsProvider{Value: creds.Credentials}))
+	case opt.CredentialsSource == "shared" || opt.Profile != "":
+		if opt.Profile != "" {
+			loadOpts = append(loadOpts, awsconfig.WithSharedConfigProfile(opt.Profile))
+		}
+	case opt.CredentialsSource != "":
+		return nil, fmt.Errorf("unknown credentials source %q", opt.CredentialsSource)
+	}
+	if urls := splitEndpoints(opt.URL); len(urls) > 0 {
+		pool := newEndpointPool(urls)
+		loadOpts = append(loadOpts, awsconfig.WithEndpointResolverWithOptions(
+			aws.EndpointResolverWithOptionsFunc(
+				func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+					return aws.Endpoint{URL: pool.pick(), HostnameImmutable: true}, nil
+				})))
+		if len(urls) > 1 {
+			// With several gateways, connection failures feed back into
+			// the pool so the next request resolves elsewhere.
+			hc := &healthClient{
+				inner: buildable,
+				pool:  pool,
+			}
+			httpClient = hc
+			loadOpts = append(loadOpts, awsconfig.WithHTTPClient(hc))
+		}
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	cli := s3v2.NewFromConfig(cfg, func(o *s3v2.Options) {
+		// Self-hosted backends (MinIO, Ceph) generally do not support
+		// virtual-host addressing.
+		o.UsePathStyle = true
+		o.HTTPSignerV4 = newSkewSigner(skew)
+	})
+	c, err := newS3Client(opt, cli)
+	if err != nil {
+		return nil, err
+	}
+	c.presigner = s3v2.NewPresignClient(cli)
+	c.httpClient = httpClient
+	return c, nil
+}
+
+// NewS3ClientWithAPI builds a client on top of an explicit backend
+// implementation instead of dialing one from opt. Tests use it with the
+// in-memory fake from the s3test package; presigning is unavailable
+// because it needs the real SDK client.
+func NewS3ClientWithAPI(opt Option, api S3API) (*s3Client, error) {
+	return newS3Client(opt, api)
+}
+
+// newS3Client wires the transfer managers, caches and background workers
+// around an already-constructed backend.
+func newS3Client(opt Option, api S3API) (*s3Client, error) {
+	cache, err := lru.New(presignCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	if opt.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", opt.Concurrency)
+	}
+	if err := validACL(opt.ACL); err != nil {
+		return nil, err
+	}
+	if opt.PartSize != 0 && int64(opt.PartSize) < manager.MinUploadPartSize {
+		return nil, fmt.Errorf("part size %s is below the S3 minimum of %d bytes", opt.PartSize, manager.MinUploadPartSize)
+	}
+	uploader := manager.NewUploader(api, func(u *manager.Uploader) {
+		if opt.PartSize != 0 {
+			u.PartSize = int64(opt.PartSize)
+		}
+		if opt.UploadConcurrency > 0 {
+			u.Concurrency = opt.UploadConcurrency
+		}
+		// A failed multipart upload must abort its own upload ID so the
+		// parts don't linger (and cost money) on the backend.
+		u.LeavePartsOnError = false
+	})
+	downloader := manager.NewDownloader(api, func(d *manager.Downloader) {
+		if opt.DownloadPartSize != 0 {
+			d.PartSize = int64(opt.DownloadPartSize)
+		}
+		if opt.DownloadConcurrency > 0 {
+			d.Concurrency = opt.DownloadConcurrency
+		}
+	})
+	c := &s3Client{
+		opt:          opt,
+		cli:          api,
+		uploader:     uploader,
+		downloader:   downloader,
+		presignCache: cache,
+		ci:           opt.configInfo(),
+		log:          opt.Logger,
+	}
+	if c.log == nil {
+		c.log = fsLogger{}
+	}
+	// The mount's file handles follow these two fields: a non-nil cache
+	// routes reads through the disk cache, a non-nil wb stages writes.
+	readCache, writeBack, err := opt.cacheMode()
+	if err != nil {
+		return nil, err
+	}
+	if readCache {
+		c.cache, err = newDiskCache(opt.CacheDir, int64(opt.CacheMaxSize))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if writeBack {
+		c.wb = newWriteback(c, opt.concurrency())
+	}
+	return c, nil
+}
+
+// opCtx applies the configured operation timeout to ctx. The returned
+// cancel must run when the operation finishes. Streaming calls whose body
+// outlives the method must not use it.
+func (c *s3Client) opCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opt.OperationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.opt.OperationTimeout)
+}
+
+// logOpError records an operation failure, demoting not-found results
+// to debug: Lookup probes keys that legitimately may not exist, and a
+// warn-level line for each miss floods the logs.
+func (c *s3Client) logOpError(err error, format string, args ...interface{}) {
+	if isNotFound(err) {
+		c.log.Debugf(format, args...)
+		return
+	}
+	c.log.Errorf(format, args...)
+}
+
+// putConfig collects per-call modifiers for PutObject and UploadObject.
+type putConfig struct {
+	ifNoneMatch bool
+	metadata    map[string]string
+}
+
+// PutOption modifies a single write request.
+type PutOption func(*putConfig)
+
+// IfNotExists makes the write conditional with If-None-Match: "*" so it only
+// succeeds when the key does not exist yet. A losing write returns
+// ErrObjectExists. This gives O_EXCL creates on the mount real semantics on
+// backends that support conditional writes.
+func IfNotExists() PutOption {
+	return func(pc *putConfig) { pc.ifNoneMatch = true }
+}
+
+// WithMetadata attaches user metadata to the written object. The keys are
+// stored under the x-amz-meta- prefix by the backend.
+func WithMetadata(md map[string]string) PutOption {
+	return func(pc *putConfig) { pc.metadata = md }
+}
+
+// PutObject writes data to bucket/path in a single request. The payload
+// is in memory, so its MD5 is sent as Content-MD5 and the backend
+// verifies the body it received; a BadDigest answer comes back as
+// ErrChecksumMismatch. UploadObject can't offer this — it streams from
+// a reader it can only traverse once.
+func (c *s3Client) PutObject(ctx context.Context, userID, bucket, path string, data []byte, opts ...PutOption) error {
+	if !c.canAccess(ctx, userID, bucket, path, actionWrite) {
+		return fmt.Errorf("put %s/%s: %w", bucket, path, ErrorPermissionDenied)
+	}
+	if len(data) == 0 && !c.opt.AllowEmptyFiles {
+		return fmt.Errorf("put %s/%s: %w", bucket, path, ErrorCantUploadEmptyFiles)
+	}
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	var pc putConfig
+	for _, o := range opts {
+		o(&pc)
+	}
+	sum := md5.Sum(data)
+	input := &s3v2.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(path),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentMD5:    aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+	}
+	if c.opt.ACL != "" {
+		input.ACL = types.ObjectCannedACL(c.opt.ACL)
+	}
+	if pc.ifNoneMatch {
+		input.IfNoneMatch = aws.String("*")
+	}
+	if len(pc.metadata) > 0 {
+		input.Metadata = c.encodeMetadata(pc.metadata)
+	}
+	if _, err := c.cli.PutObject(ctx, input); err != nil {
+		counters.record("PutObject", err)
+		if pc.ifNoneMatch && isPreconditionFailed(err) {
+			return fmt.Errorf("put %s/%s: %w", bucket, path, ErrObjectExists)
+		}
+		if apiErrorCode(err) == "BadDigest" {
+			return fmt.Errorf("put %s/%s: %w: %w", bucket, path, ErrChecksumMismatch, err)
+		}
+		c.logOpError(err, "put object %s/%s error: %v", bucket, path, err)
+		return fmt.Errorf("put %s/%s: %w", bucket, path, classify(err))
+	}
+	counters.record("PutObject", nil)
+	counters.bytesUp.Add(uint64(len(data)))
+	return nil
+}
+
+// UploadObject streams reader to bucket/path through the transfer manager,
+// using multipart upload for large payloads. The body is consumed as it
+// is read, so unlike PutObject no Content-MD5 can be computed up front;
+// multipart integrity rests on the per-part ETags instead.
+func (c *s3Client) UploadObject(ctx context.Context, userID, bucket, path string, reader io.Reader, opts ...PutOption) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	var pc putConfig
+	for _, o := range opts {
+		o(&pc)
+	}
+	if !c.opt.AllowEmptyFiles {
+		// The stream's length is unknown up front, so peek one byte to
+		// tell a genuinely empty body apart before anything is sent.
+		var first [1]byte
+		n, err := reader.Read(first[:])
+		if n == 0 {
+			if err == io.EOF {
+				return fmt.Errorf("upload %s/%s: %w", bucket, path, ErrorCantUploadEmptyFiles)
+			}
+			if err != nil {
+				return fmt.Errorf("upload %s/%s: %w", bucket, path, err)
+			}
+		}
+		reader = io.MultiReader(bytes.NewReader(first[:n]), reader)
+	}
+	input := &s3v2.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+		Body:   meteredReader{r: reader},
+	}
+	if c.opt.ACL != "" {
+		input.ACL = types.ObjectCannedACL(c.opt.ACL)
+	}
+	if pc.ifNoneMatch {
+		input.IfNoneMatch = aws.String("*")
+	}
+	if len(pc.metadata) > 0 {
+		input.Metadata = c.encodeMetadata(pc.metadata)
+	}
+	if _, err := c.uploader.Upload(ctx, input); err != nil {
+		counters.record("UploadObject", err)
+		if pc.ifNoneMatch && isPreconditionFailed(err) {
+			return fmt.Errorf("upload %s/%s: %w", bucket, path, ErrObjectExists)
+		}
+		c.logOpError(err, "upload object %s/%s error: %v", bucket, path, err)
+		return fmt.Errorf("upload %s/%s: %w", bucket, path, classify(err))
+	}
+	counters.record("UploadObject", nil)
+	return nil
+}
+
+// HeadObject returns the metadata of bucket/path without the body.
+func (c *s3Client) HeadObject(ctx context.Context, userID, bucket, path string) (*Object, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.HeadObject(ctx, &s3v2.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+	})
+	counters.record("HeadObject", err)
+	if err != nil {
+		return nil, fmt.Errorf("head %s/%s: %w", bucket, path, classify(err))
+	}
+	return &Object{
+		Key:          path,
+		Size:         aws.ToInt64(out.ContentLength),
+		LastModified: aws.ToTime(out.LastModified),
+		ETag:         aws.ToString(out.ETag),
+		Metadata:     c.decodeMetadata(out.Metadata),
+		StorageClass: string(out.StorageClass),
+	}, nil
+}
+
+// ObjectExists reports whether bucket/path exists, with a HeadObject and
+// no data transfer. A plain 404 is not an error, just false.
+func (c *s3Client) ObjectExists(ctx context.Context, userID, bucket, path string) (bool, error) {
+	_, err := c.HeadObject(ctx, userID, bucket, path)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetObject downloads the whole of bucket/path into memory in a single
+// round-trip: the GetObject response carries both ContentLength, which
+// sizes the buffer exactly, and the body. Earlier versions issued a
+// HeadObject first and handed the transfer manager the download, which
+// cost two sequential round-trips before the first byte arrived —
+// dropping the Head halves time-to-first-byte for the small objects
+// this whole-body path is used for. Large streaming reads still go
+// through DownloadTo and keep the transfer manager's parallel parts.
+func (c *s3Client) GetObject(ctx context.Context, userID, bucket, path string) ([]byte, error) {
+	if !c.canAccess(ctx, userID, bucket, path, actionRead) {
+		return nil, fmt.Errorf("get %s/%s: %w", bucket, path, ErrorPermissionDenied)
+	}
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetObject(ctx, &s3v2.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+	})
+	counters.record("GetObject", err)
+	if err != nil {
+		c.logOpError(err, "get object %s/%s error: %v", bucket, path, err)
+		return nil, fmt.Errorf("get %s/%s: %w", bucket, path, classify(err))
+	}
+	defer out.Body.Close()
+	buf := bytes.NewBuffer(make([]byte, 0, aws.ToInt64(out.ContentLength)))
+	if _, err := io.Copy(buf, out.Body); err != nil {
+		return nil, fmt.Errorf("read %s/%s: %w", bucket, path, err)
+	}
+	counters.bytesDown.Add(uint64(buf.Len()))
+	return buf.Bytes(), nil
+}
+
+// DownloadTo streams bucket/path into w through the transfer manager,
+// returning the number of bytes written. Unlike GetObject it allocates
+// nothing beyond the download parts, so it suits sinks like the disk
+// cache or an already-mapped read buffer.
+func (c *s3Client) DownloadTo(ctx context.Context, userID, bucket, path string, w io.WriterAt) (int64, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	n, err := c.downloader.Download(ctx, w, &s3v2.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+	})
+	counters.record("GetObject", err)
+	if err != nil {
+		c.logOpError(err, "download object %s/%s error: %v", bucket, path, err)
+		return n, fmt.Errorf("download %s/%s: %w", bucket, path, classify(err))
+	}
+	counters.bytesDown.Add(uint64(n))
+	return n, nil
+}
+
+// GetObjectToFile streams bucket/path straight into a new file at
+// localPath, returning the bytes written. The downloader writes parts
+// through the file's WriterAt, so memory stays flat however large the
+// object is — this is how the disk cache is populated in one step. On
+// any failure the partial file is removed so a later cache lookup can't
+// mistake it for a complete body.
+func (c *s3Client) GetObjectToFile(ctx context.Context, userID, bucket, path, localPath string) (int64, error) {
+	f, err := os.Create(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", localPath, err)
+	}
+	n, err := c.DownloadTo(ctx, userID, bucket, path, f)
+	if err != nil {
+		f.Close()
+		os.Remove(localPath)
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(localPath)
+		return 0, fmt.Errorf("close %s: %w", localPath, err)
+	}
+	return n, nil
+}
+
+// GetObjectReader returns a streaming reader over bucket/path, wrapped in
+// an S3Node so accounting and the configured bandwidth limit apply.
+func (c *s3Client) GetObjectReader(ctx context.Context, userID, bucket, path string) (*S3Node, error) {
+	out, err := c.cli.GetObject(ctx, &s3v2.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		c.logOpError(err, "get object %s/%s error: %v", bucket, path, err)
+		return nil, fmt.Errorf("get %s/%s: %w", bucket, path, classify(err))
+	}
+	return newS3Node(ctx, out.Body, aws.ToInt64(out.ContentLength), bucket+"/"+path, c.ci), nil
+}
+
+// ListObject lists one level of bucket under prefix using "/" as delimiter.
+// Common prefixes are returned as directory entries.
+func (c *s3Client) ListObject(ctx context.Context, userID, bucket, prefix string) ([]Object, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	var objs []Object
+	p := s3v2.NewListObjectsV2Paginator(c.cli, &s3v2.ListObjectsV2Input{
+		Bucket:    aws.String(bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		counters.record("ListObjectsV2", err)
+		if err != nil {
+			c.log.Errorf("list objects %s/%s error: %v", bucket, prefix, err)
+			return nil, fmt.Errorf("list %s/%s: %w", bucket, prefix, classify(err))
+		}
+		for _, cp := range page.CommonPrefixes {
+			objs = append(objs, Object{Key: aws.ToString(cp.Prefix), IsDir: true})
+		}
+		for _, obj := range page.Contents {
+			objs = append(objs, Object{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+				ETag:         aws.ToString(obj.ETag),
+			})
+		}
+	}
+	return objs, nil
+}
+
+// UpdateObjectMetadata replaces the content type and user metadata of
+// bucket/path in place. S3 objects are immutable, so this is a self-copy
+// with MetadataDirective REPLACE — the backend rewrites the metadata
+// without the data ever crossing the wire. An empty contentType keeps
+// the backend's default.
+func (c *s3Client) UpdateObjectMetadata(ctx context.Context, userID, bucket, path string, contentType string, metadata map[string]string) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	input := &s3v2.CopyObjectInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(path),
+		CopySource:        aws.String(url.PathEscape(bucket + "/" + path)),
+		MetadataDirective: types.MetadataDirectiveReplace,
+		Metadata:          c.encodeMetadata(metadata),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if _, err := c.cli.CopyObject(ctx, input); err != nil {
+		c.logOpError(err, "update metadata %s/%s error: %v", bucket, path, err)
+		return fmt.Errorf("update metadata %s/%s: %w", bucket, path, classify(err))
+	}
+	return nil
+}
+
+// RestoreObject asks the backend to bring an archived object (GLACIER,
+// DEEP_ARCHIVE) back online for days days. tier picks the retrieval
+// speed — Standard, Bulk or Expedited — with empty meaning Standard.
+// The call only starts the restore; reads keep failing with
+// ErrObjectArchived until it completes, which takes minutes to hours
+// depending on the tier.
+func (c *s3Client) RestoreObject(ctx context.Context, bucket, key string, days int, tier string) error {
+	if days < 1 {
+		return fmt.Errorf("restore %s/%s: days must be at least 1, got %d", bucket, key, days)
+	}
+	if tier == "" {
+		tier = string(types.TierStandard)
+	}
+	switch types.Tier(tier) {
+	case types.TierStandard, types.TierBulk, types.TierExpedited:
+	default:
+		return fmt.Errorf("restore %s/%s: unknown retrieval tier %q", bucket, key, tier)
+	}
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	_, err := c.cli.RestoreObject(ctx, &s3v2.RestoreObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		RestoreRequest: &types.RestoreRequest{
+			Days:                 aws.Int32(int32(days)),
+			GlacierJobParameters: &types.GlacierJobParameters{Tier: types.Tier(tier)},
+		},
+	})
+	if err != nil {
+		// RestoreAlreadyInProgress is success for our purposes: the data
+		// is on its way.
+		if apiErrorCode(err) == "RestoreAlreadyInProgress" {
+			return nil
+		}
+		c.logOpError(err, "restore object %s/%s error: %v", bucket, key, err)
+		return fmt.Errorf("restore %s/%s: %w", bucket, key, classify(err))
+	}
+	return nil
+}
+
+// GetObjectIfChanged downloads bucket/path only if its ETag differs
+// from etag, using a conditional GET. It returns (nil, false, nil) when
+// the object is unchanged, so revalidating a cached copy costs one
+// round-trip and no body transfer.
+func (c *s3Client) GetObjectIfChanged(ctx context.Context, userID, bucket, path, etag string) ([]byte, bool, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetObject(ctx, &s3v2.GetObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(path),
+		IfNoneMatch: aws.String(etag),
+	})
+	if err != nil {
+		if isNotModified(err) {
+			return nil, false, nil
+		}
+		c.logOpError(err, "get object %s/%s error: %v", bucket, path, err)
+		return nil, false, fmt.Errorf("get %s/%s: %w", bucket, path, classify(err))
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("get %s/%s: %w", bucket, path, err)
+	}
+	return data, true, nil
+}
+
+// CopyObject copies bucket/src to bucket/dst server-side, keeping the
+// source object and its metadata intact.
+func (c *s3Client) CopyObject(ctx context.Context, userID, bucket, src, dst string) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	_, err := c.cli.CopyObject(ctx, &s3v2.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(dst),
+		CopySource: aws.String(url.PathEscape(bucket + "/" + src)),
+	})
+	counters.record("CopyObject", err)
+	if err != nil {
+		c.logOpError(err, "copy %s/%s to %s error: %v", bucket, src, dst, err)
+		return fmt.Errorf("copy %s/%s to %s: %w", bucket, src, dst, classify(err))
+	}
+	return nil
+}
+
+// ListObjectRecursiveFunc pages through every key under prefix without a
+// delimiter, calling fn for each object as it arrives so arbitrarily
+// large trees can be processed without buffering. A non-nil error from
+// fn stops the walk and is returned.
+func (c *s3Client) ListObjectRecursiveFunc(ctx context.Context, userID, bucket, prefix string, fn func(Object) error) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	p := s3v2.NewListObjectsV2Paginator(c.cli, &s3v2.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			c.log.Errorf("list objects %s/%s error: %v", bucket, prefix, err)
+			return fmt.Errorf("list %s/%s: %w", bucket, prefix, classify(err))
+		}
+		for _, obj := range page.Contents {
+			err := fn(Object{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+				ETag:         aws.ToString(obj.ETag),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListObjectRecursive returns every key under prefix as a flat slice.
+// For trees too large to hold in memory use ListObjectRecursiveFunc.
+func (c *s3Client) ListObjectRecursive(ctx context.Context, userID, bucket, prefix string) ([]Object, error) {
+	var objs []Object
+	err := c.ListObjectRecursiveFunc(ctx, userID, bucket, prefix, func(obj Object) error {
+		objs = append(objs, obj)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// ListObjectParallel lists every key under each prefix shard with
+// concurrent paginators, bounded by the concurrency option, and merges
+// the results sorted by key. Enumerating a huge bucket goes much faster
+// when the caller knows how its keys fan out — say one shard per leading
+// hex character — because S3 paginates each shard independently. The
+// shards are assumed disjoint; overlapping prefixes list their
+// intersection twice. The first failing shard cancels the rest.
+func (c *s3Client) ListObjectParallel(ctx context.Context, bucket string, prefixes []string) ([]Object, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, c.opt.concurrency())
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	shards := make([][]Object, len(prefixes))
+	for i, prefix := range prefixes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, prefix string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			objs, err := c.ListObjectRecursive(ctx, "", bucket, prefix)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			shards[i] = objs
+		}(i, prefix)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	var merged []Object
+	for _, objs := range shards {
+		merged = append(merged, objs...)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Key < merged[j].Key })
+	return merged, nil
+}
+
+// DeleteObject removes bucket/path.
+func (c *s3Client) DeleteObject(ctx context.Context, userID, bucket, path string) error {
+	if !c.canAccess(ctx, userID, bucket, path, actionDelete) {
+		return fmt.Errorf("delete %s/%s: %w", bucket, path, ErrorPermissionDenied)
+	}
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	_, err := c.cli.DeleteObject(ctx, &s3v2.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+	})
+	counters.record("DeleteObject", err)
+	if err != nil {
+		c.logOpError(err, "delete object %s/%s error: %v", bucket, path, err)
+		return fmt.Errorf("delete %s/%s: %w", bucket, path, classify(err))
+	}
+	return nil
+}
+
+// ListBuckets returns all buckets visible to the credentials.
+func (c *s3Client) ListBuckets(ctx context.Context, userID string) ([]Bucket, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.ListBuckets(ctx, &s3v2.ListBucketsInput{})
+	if err != nil {
+		c.log.Errorf("list buckets error: %v", err)
+		return nil, fmt.Errorf("list buckets: %w", classify(err))
+	}
+	buckets := make([]Bucket, 0, len(out.Buckets))
+	for _, b := range out.Buckets {
+		buckets = append(buckets, Bucket{
+			Name:      aws.ToString(b.Name),
+			CreatedAt: aws.ToTime(b.CreationDate),
+		})
+	}
+	return buckets, nil
+}
+
+// deleteBatchSize is the DeleteObjects limit per request.
+const deleteBatchSize = 1000
+
+// deleteBatch removes up to deleteBatchSize keys in one DeleteObjects
+// call, reporting the first per-key error the backend returns.
+func (c *s3Client) deleteBatch(ctx context.Context, bucket string, keys []string) error {
+	ids := make([]types.ObjectIdentifier, len(keys))
+	for i, key := range keys {
+		ids[i] = types.ObjectIdentifier{Key: aws.String(key)}
+	}
+	out, err := c.cli.DeleteObjects(ctx, &s3v2.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		c.log.Errorf("delete objects in %s error: %v", bucket, err)
+		return fmt.Errorf("delete objects in %s: %w", bucket, classify(err))
+	}
+	if len(out.Errors) > 0 {
+		e := out.Errors[0]
+		return fmt.Errorf("delete %s/%s: %s", bucket, aws.ToString(e.Key), aws.ToString(e.Message))
+	}
+	return nil
+}
+
+// Purge deletes every key under prefix in batched DeleteObjects calls,
+// leaving the bucket itself in place. An empty prefix empties the whole
+// bucket.
+func (c *s3Client) Purge(ctx context.Context, userID, bucket, prefix string) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	p := s3v2.NewListObjectsV2Paginator(c.cli, &s3v2.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	var batch []string
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			c.log.Errorf("list %s/%s error: %v", bucket, prefix, err)
+			return fmt.Errorf("list %s/%s: %w", bucket, prefix, classify(err))
+		}
+		for _, obj := range page.Contents {
+			batch = append(batch, aws.ToString(obj.Key))
+			if len(batch) == deleteBatchSize {
+				if err := c.deleteBatch(ctx, bucket, batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return c.deleteBatch(ctx, bucket, batch)
+	}
+	return nil
+}
+
+// CreateBucket creates a bucket owned by userID and returns its creation
+// info. A bucket we already own is success, not an error — the operation
+// is idempotent that way — while BucketAlreadyExists (someone else's
+// bucket) still fails. An optional tags map is applied right after
+// creation, so cost-allocation tags never lag behind the bucket; a
+// tagging failure is reported but leaves the created bucket in place.
+func (c *s3Client) CreateBucket(ctx context.Context, userID, name string, tags ...map[string]string) (*Bucket, error) {
+	if err := ValidBucketName(name); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	input := &s3v2.CreateBucketInput{
+		Bucket: aws.String(name),
+	}
+	if acl := c.bucketACL(); acl != "" {
+		input.ACL = acl
+	}
+	// us-east-1 is the one region that must NOT be sent as a location
+	// constraint; everywhere else requires it.
+	if c.opt.Region != "" && c.opt.Region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(c.opt.Region),
+		}
+	}
+	bkt := &Bucket{Name: name, Region: c.opt.Region, CreatedAt: time.Now()}
+	if _, err := c.cli.CreateBucket(ctx, input); err != nil {
+		if apiErrorCode(err) == "BucketAlreadyOwnedByYou" {
+			return bkt, nil
+		}
+		c.log.Errorf("create bucket %s error: %v", name, err)
+		return nil, fmt.Errorf("create bucket %s: %w", name, classify(err))
+	}
+	for _, t := range tags {
+		if len(t) == 0 {
+			continue
+		}
+		if err := c.PutBucketTagging(ctx, name, t); err != nil {
+			return bkt, err
+		}
+	}
+	return bkt, nil
+}
+
+// DeleteBucket removes every object in the bucket and then the bucket
+// itself.
+func (c *s3Client) DeleteBucket(ctx context.Context, userID, name string) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	p := s3v2.NewListObjectsV2Paginator(c.cli, &s3v2.ListObjectsV2Input{
+		Bucket: aws.String(name),
+	})
+	sem := make(chan struct{}, c.opt.concurrency())
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			c.log.Errorf("list bucket %s error: %v", name, err)
+			return fmt.Errorf("list bucket %s: %w", name, classify(err))
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := c.DeleteObject(ctx, userID, name, key); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	// Revoke shares before the bucket goes; the policy would die with the
+	// bucket anyway, so a failure here is worth a warning but shouldn't
+	// block the delete.
+	if err := c.deleteBucketShares(ctx, userID, name); err != nil {
+		c.log.Errorf("delete shares of bucket %s error: %v", name, err)
+	}
+	if _, err := c.cli.DeleteBucket(ctx, &s3v2.DeleteBucketInput{Bucket: aws.String(name)}); err != nil {
+		c.log.Errorf("delete bucket %s error: %v", name, err)
+		return fmt.Errorf("delete bucket %s: %w", name, classify(err))
+	}
+	return nil
+}
+
+// PutBucketPolicy validates policy and applies it to bucket. Validation
+// failures are reported before any request is made.
+func (c *s3Client) PutBucketPolicy(ctx context.Context, bucket string, policy *BucketPolicy) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	doc, err := policy.ToString()
+	if err != nil {
+		return fmt.Errorf("invalid policy for bucket %s: %w", bucket, err)
+	}
+	_, err = c.cli.PutBucketPolicy(ctx, &s3v2.PutBucketPolicyInput{
+		Bucket: aws.String(bucket),
+		Policy: aws.String(doc),
+	})
+	if err != nil {
+		c.log.Errorf("put bucket policy %s error: %v", bucket, err)
+		return fmt.Errorf("put bucket policy %s: %w", bucket, classify(err))
+	}
+	c.invalidatePolicy(bucket)
+	return nil
+}
+
+// ObjectVersion describes one version of a key in a versioned bucket.
+type ObjectVersion struct {
+	Key          string
+	VersionID    string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	// IsLatest marks the version a plain GetObject would return.
+	IsLatest bool
+}
+
+// ListObjectVersions returns every version of every key under prefix,
+// newest first per key. Buckets that never had versioning enabled
+// return one entry per current object.
+func (c *s3Client) ListObjectVersions(ctx context.Context, bucket, prefix string) ([]ObjectVersion, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	var versions []ObjectVersion
+	input := &s3v2.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		out, err := c.cli.ListObjectVersions(ctx, input)
+		if err != nil {
+			c.log.Errorf("list object versions %s/%s error: %v", bucket, prefix, err)
+			return nil, fmt.Errorf("list object versions %s/%s: %w", bucket, prefix, classify(err))
+		}
+		for _, v := range out.Versions {
+			versions = append(versions, ObjectVersion{
+				Key:          aws.ToString(v.Key),
+				VersionID:    aws.ToString(v.VersionId),
+				Size:         aws.ToInt64(v.Size),
+				LastModified: aws.ToTime(v.LastModified),
+				ETag:         aws.ToString(v.ETag),
+				IsLatest:     aws.ToBool(v.IsLatest),
+			})
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			return versions, nil
+		}
+		input.KeyMarker = out.NextKeyMarker
+		input.VersionIdMarker = out.NextVersionIdMarker
+	}
+}
+
+// GetObjectVersion downloads one specific version of bucket/key.
+func (c *s3Client) GetObjectVersion(ctx context.Context, bucket, key, versionID string) ([]byte, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetObject(ctx, &s3v2.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		c.logOpError(err, "get object version %s/%s@%s error: %v", bucket, key, versionID, err)
+		return nil, fmt.Errorf("get %s/%s@%s: %w", bucket, key, versionID, classify(err))
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s/%s@%s: %w", bucket, key, versionID, err)
+	}
+	return data, nil
+}
+
+// SetBucketVersioning enables or suspends versioning on bucket. S3 has
+// no way to turn versioning fully off once it has been on; disabling
+// suspends it, which stops new versions from accumulating.
+func (c *s3Client) SetBucketVersioning(ctx context.Context, bucket string, enabled bool) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	status := types.BucketVersioningStatusSuspended
+	if enabled {
+		status = types.BucketVersioningStatusEnabled
+	}
+	_, err := c.cli.PutBucketVersioning(ctx, &s3v2.PutBucketVersioningInput{
+		Bucket:                  aws.String(bucket),
+		VersioningConfiguration: &types.VersioningConfiguration{Status: status},
+	})
+	if err != nil {
+		c.log.Errorf("put bucket versioning %s error: %v", bucket, err)
+		return fmt.Errorf("put bucket versioning %s: %w", bucket, classify(err))
+	}
+	return nil
+}
+
+// GetBucketVersioning reports whether versioning is currently enabled on
+// bucket. Buckets that never had versioning configured report false.
+func (c *s3Client) GetBucketVersioning(ctx context.Context, bucket string) (bool, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetBucketVersioning(ctx, &s3v2.GetBucketVersioningInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		c.log.Errorf("get bucket versioning %s error: %v", bucket, err)
+		return false, fmt.Errorf("get bucket versioning %s: %w", bucket, classify(err))
+	}
+	return out.Status == types.BucketVersioningStatusEnabled, nil
+}
+
+// AbortIncompleteUploads aborts multipart uploads under prefix that were
+// started more than olderThan ago, returning how many were aborted.
+// Interrupted uploads (crashes, lost connections) leave orphaned parts that
+// are invisible in listings but still billed; running this periodically
+// cleans them out.
+func (c *s3Client) AbortIncompleteUploads(ctx context.Context, bucket, prefix string, olderThan time.Duration) (int, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	cutoff := time.Now().Add(-olderThan)
+	aborted := 0
+	input := &s3v2.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		out, err := c.cli.ListMultipartUploads(ctx, input)
+		if err != nil {
+			c.log.Errorf("list multipart uploads %s/%s error: %v", bucket, prefix, err)
+			return aborted, fmt.Errorf("list multipart uploads %s/%s: %w", bucket, prefix, classify(err))
+		}
+		for _, up := range out.Uploads {
+			if aws.ToTime(up.Initiated).After(cutoff) {
+				continue
+			}
+			_, err := c.cli.AbortMultipartUpload(ctx, &s3v2.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucket),
+				Key:      up.Key,
+				UploadId: up.UploadId,
+			})
+			if err != nil {
+				c.log.Errorf("abort multipart upload %s/%s (%s) error: %v",
+					bucket, aws.ToString(up.Key), aws.ToString(up.UploadId), err)
+				return aborted, fmt.Errorf("abort multipart upload %s/%s: %w",
+					bucket, aws.ToString(up.Key), classify(err))
+			}
+			aborted++
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			return aborted, nil
+		}
+		input.KeyMarker = out.NextKeyMarker
+		input.UploadIdMarker = out.NextUploadIdMarker
+	}
+}
+
+// presignEntry is one cached presigned link together with the time it
+// stops working, so the cache never hands out a dead URL.
+type presignEntry struct {
+	URL    string
+	Expiry time.Time
+}
+
+// presignSlack is subtracted from an entry's lifetime so a link that is
+// about to expire isn't served to a caller who then loses the race.
+const presignSlack = 30 * time.Second
+
+// PresignObject returns a time limited download URL for bucket/path.
+func (c *s3Client) PresignObject(ctx context.Context, userID, bucket, path string) (string, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	if c.presigner == nil {
+		return "", fmt.Errorf("presign %s/%s: backend does not support presigning", bucket, path)
+	}
+	cacheKey := bucket + "/" + path
+	if v, ok := c.presignCache.Get(cacheKey); ok {
+		entry := v.(presignEntry)
+		if time.Now().Before(entry.Expiry) {
+			return entry.URL, nil
+		}
+		c.presignCache.Remove(cacheKey)
+	}
+	req, err := c.presigner.PresignGetObject(ctx, &s3v2.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+	}, s3v2.WithPresignExpires(presignExpiry))
+	if err != nil {
+		c.log.Errorf("presign object %s/%s error: %v", bucket, path, err)
+		return "", fmt.Errorf("presign %s/%s: %w", bucket, path, classify(err))
+	}
+	c.presignCache.Add(cacheKey, presignEntry{
+		URL:    req.URL,
+		Expiry: time.Now().Add(presignExpiry - presignSlack),
+	})
+	return req.URL, nil
+}
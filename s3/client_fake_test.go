@@ -0,0 +1,437 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/ThierryZhou/go-s3fs/s3/s3test"
+)
+
+// fakeClient returns a client backed by the in-memory s3test backend
+// with one empty bucket, for unit tests that need no real endpoint.
+func fakeClient(t *testing.T, bucket string) *s3Client {
+	t.Helper()
+	cli, err := NewS3ClientWithAPI(defaultOption, s3test.New())
+	if err != nil {
+		t.Fatalf("NewS3ClientWithAPI: %v", err)
+	}
+	if _, err := cli.CreateBucket(context.Background(), "test", bucket); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	return cli
+}
+
+func TestFakeObjectRoundTrip(t *testing.T) {
+	cli := fakeClient(t, "round-trip")
+	ctx := context.Background()
+
+	if err := cli.PutObject(ctx, "test", "round-trip", "dir/hello", []byte("world")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	data, err := cli.GetObject(ctx, "test", "round-trip", "dir/hello")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	if string(data) != "world" {
+		t.Fatalf("GetObject = %q, want %q", data, "world")
+	}
+
+	objs, err := cli.ListObject(ctx, "test", "round-trip", "")
+	if err != nil {
+		t.Fatalf("ListObject: %v", err)
+	}
+	if len(objs) != 1 || !objs[0].IsDir || objs[0].Key != "dir/" {
+		t.Fatalf("ListObject = %+v, want single dir/ prefix", objs)
+	}
+
+	if err := cli.DeleteObject(ctx, "test", "round-trip", "dir/hello"); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+	if _, err := cli.GetObject(ctx, "test", "round-trip", "dir/hello"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("GetObject after delete: got %v, want ErrNoSuchKey", err)
+	}
+}
+
+func TestGetObjectIfChanged(t *testing.T) {
+	cli := fakeClient(t, "cond-get")
+	ctx := context.Background()
+	if err := cli.PutObject(ctx, "test", "cond-get", "k", []byte("v1")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	head, err := cli.HeadObject(ctx, "test", "cond-get", "k")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+
+	data, changed, err := cli.GetObjectIfChanged(ctx, "test", "cond-get", "k", head.ETag)
+	if err != nil {
+		t.Fatalf("GetObjectIfChanged: %v", err)
+	}
+	if changed || data != nil {
+		t.Fatalf("unchanged object: got changed=%v data=%q, want 304", changed, data)
+	}
+
+	if err := cli.PutObject(ctx, "test", "cond-get", "k", []byte("v2")); err != nil {
+		t.Fatalf("PutObject v2: %v", err)
+	}
+	data, changed, err = cli.GetObjectIfChanged(ctx, "test", "cond-get", "k", head.ETag)
+	if err != nil {
+		t.Fatalf("GetObjectIfChanged after rewrite: %v", err)
+	}
+	if !changed || string(data) != "v2" {
+		t.Fatalf("changed object: got changed=%v data=%q, want v2", changed, data)
+	}
+}
+
+// TestGetObjectMissingKeyReturnsTypedError pins down that a missing key
+// produces ErrorObjectNotFound and no placeholder data. An earlier
+// incarnation of GetObject answered missing keys with literal "{}",
+// which silently corrupted any non-JSON consumer.
+func TestGetObjectMissingKeyReturnsTypedError(t *testing.T) {
+	cli := fakeClient(t, "absent")
+	data, err := cli.GetObject(context.Background(), "test", "absent", "no/such/key")
+	if !errors.Is(err, ErrorObjectNotFound) {
+		t.Fatalf("GetObject of missing key: got %v, want ErrorObjectNotFound", err)
+	}
+	if len(data) != 0 {
+		t.Fatalf("GetObject of missing key returned data %q, want none", data)
+	}
+}
+
+func TestListObjectRecursive(t *testing.T) {
+	cli := fakeClient(t, "recurse")
+	ctx := context.Background()
+	for _, key := range []string{"a", "dir/b", "dir/sub/c", "other/d"} {
+		if err := cli.PutObject(ctx, "test", "recurse", key, []byte(key)); err != nil {
+			t.Fatalf("PutObject %s: %v", key, err)
+		}
+	}
+
+	objs, err := cli.ListObjectRecursive(ctx, "test", "recurse", "dir/")
+	if err != nil {
+		t.Fatalf("ListObjectRecursive: %v", err)
+	}
+	if len(objs) != 2 || objs[0].Key != "dir/b" || objs[1].Key != "dir/sub/c" {
+		t.Fatalf("ListObjectRecursive = %+v, want dir/b and dir/sub/c", objs)
+	}
+
+	// The callback variant must stop on the first error from fn.
+	calls := 0
+	sentinel := errors.New("stop")
+	err = cli.ListObjectRecursiveFunc(ctx, "test", "recurse", "", func(Object) error {
+		calls++
+		return sentinel
+	})
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("ListObjectRecursiveFunc error = %v, want sentinel", err)
+	}
+	if calls != 1 {
+		t.Fatalf("fn called %d times after returning an error, want 1", calls)
+	}
+}
+
+func TestDownloadTo(t *testing.T) {
+	cli := fakeClient(t, "dlto")
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("s3fs"), 1024)
+
+	if err := cli.PutObject(ctx, "test", "dlto", "blob", content); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	buf := manager.NewWriteAtBuffer(nil)
+	n, err := cli.DownloadTo(ctx, "test", "dlto", "blob", buf)
+	if err != nil {
+		t.Fatalf("DownloadTo: %v", err)
+	}
+	if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+		t.Fatalf("DownloadTo wrote %d bytes, want %d matching bytes", n, len(content))
+	}
+}
+
+func TestUpdateObjectMetadata(t *testing.T) {
+	cli := fakeClient(t, "meta")
+	ctx := context.Background()
+
+	if err := cli.PutObject(ctx, "test", "meta", "key", []byte("data"), WithMetadata(map[string]string{"old": "1"})); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	if err := cli.UpdateObjectMetadata(ctx, "test", "meta", "key", "", map[string]string{"mode": "644"}); err != nil {
+		t.Fatalf("UpdateObjectMetadata: %v", err)
+	}
+	head, err := cli.HeadObject(ctx, "test", "meta", "key")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if head.Metadata["mode"] != "644" || head.Metadata["old"] != "" {
+		t.Fatalf("metadata = %v, want replaced with mode=644", head.Metadata)
+	}
+	// The body must be untouched by a metadata-only update.
+	data, err := cli.GetObject(ctx, "test", "meta", "key")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	if string(data) != "data" {
+		t.Fatalf("content after metadata update = %q, want %q", data, "data")
+	}
+}
+
+func TestBucketVersioningToggle(t *testing.T) {
+	cli := fakeClient(t, "versioned")
+	ctx := context.Background()
+
+	enabled, err := cli.GetBucketVersioning(ctx, "versioned")
+	if err != nil {
+		t.Fatalf("GetBucketVersioning: %v", err)
+	}
+	if enabled {
+		t.Fatal("fresh bucket reports versioning enabled")
+	}
+	if err := cli.SetBucketVersioning(ctx, "versioned", true); err != nil {
+		t.Fatalf("SetBucketVersioning(true): %v", err)
+	}
+	if enabled, err = cli.GetBucketVersioning(ctx, "versioned"); err != nil || !enabled {
+		t.Fatalf("after enabling: enabled=%v err=%v", enabled, err)
+	}
+	if err := cli.SetBucketVersioning(ctx, "versioned", false); err != nil {
+		t.Fatalf("SetBucketVersioning(false): %v", err)
+	}
+	if enabled, err = cli.GetBucketVersioning(ctx, "versioned"); err != nil || enabled {
+		t.Fatalf("after suspending: enabled=%v err=%v", enabled, err)
+	}
+}
+
+func TestObjectVersions(t *testing.T) {
+	cli := fakeClient(t, "hist")
+	ctx := context.Background()
+
+	if err := cli.SetBucketVersioning(ctx, "hist", true); err != nil {
+		t.Fatalf("SetBucketVersioning: %v", err)
+	}
+	for _, content := range []string{"v1", "v2"} {
+		if err := cli.PutObject(ctx, "test", "hist", "doc", []byte(content)); err != nil {
+			t.Fatalf("PutObject %s: %v", content, err)
+		}
+	}
+
+	versions, err := cli.ListObjectVersions(ctx, "hist", "")
+	if err != nil {
+		t.Fatalf("ListObjectVersions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("got %d versions, want 2", len(versions))
+	}
+	if !versions[0].IsLatest || versions[1].IsLatest {
+		t.Fatalf("versions = %+v, want newest first marked latest", versions)
+	}
+
+	data, err := cli.GetObjectVersion(ctx, "hist", "doc", versions[1].VersionID)
+	if err != nil {
+		t.Fatalf("GetObjectVersion: %v", err)
+	}
+	if string(data) != "v1" {
+		t.Fatalf("old version content = %q, want v1", data)
+	}
+	if _, err := cli.GetObjectVersion(ctx, "hist", "doc", "no-such"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("missing version: got %v, want ErrNoSuchKey", err)
+	}
+}
+
+func TestFakeConditionalPut(t *testing.T) {
+	cli := fakeClient(t, "cond")
+	ctx := context.Background()
+
+	if err := cli.PutObject(ctx, "test", "cond", "key", []byte("a"), IfNotExists()); err != nil {
+		t.Fatalf("first conditional put: %v", err)
+	}
+	err := cli.PutObject(ctx, "test", "cond", "key", []byte("b"), IfNotExists())
+	if !errors.Is(err, ErrObjectExists) {
+		t.Fatalf("second conditional put: got %v, want ErrObjectExists", err)
+	}
+}
+
+func TestEmptyUploadRespectsAllowEmptyFiles(t *testing.T) {
+	ctx := context.Background()
+
+	// The default permits zero-byte objects, which touch and Mkdir rely on.
+	cli := fakeClient(t, "empty-ok")
+	if err := cli.PutObject(ctx, "test", "empty-ok", "touched", []byte{}); err != nil {
+		t.Fatalf("PutObject empty with default option: %v", err)
+	}
+
+	opt := defaultOption
+	opt.AllowEmptyFiles = false
+	strict, err := NewS3ClientWithAPI(opt, s3test.New())
+	if err != nil {
+		t.Fatalf("NewS3ClientWithAPI: %v", err)
+	}
+	if _, err := strict.CreateBucket(ctx, "test", "empty-no"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if err := strict.PutObject(ctx, "test", "empty-no", "touched", nil); !errors.Is(err, ErrorCantUploadEmptyFiles) {
+		t.Fatalf("PutObject empty: got %v, want ErrorCantUploadEmptyFiles", err)
+	}
+	if err := strict.UploadObject(ctx, "test", "empty-no", "streamed", bytes.NewReader(nil)); !errors.Is(err, ErrorCantUploadEmptyFiles) {
+		t.Fatalf("UploadObject empty: got %v, want ErrorCantUploadEmptyFiles", err)
+	}
+	// A non-empty stream must survive the one-byte peek intact.
+	if err := strict.UploadObject(ctx, "test", "empty-no", "streamed", bytes.NewReader([]byte("payload"))); err != nil {
+		t.Fatalf("UploadObject: %v", err)
+	}
+	data, err := strict.GetObject(ctx, "test", "empty-no", "streamed")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	if string(data) != "payload" {
+		t.Fatalf("GetObject = %q, want %q", data, "payload")
+	}
+}
+
+func TestArchivedObjectRestoreCycle(t *testing.T) {
+	backend := s3test.New()
+	cli, err := NewS3ClientWithAPI(defaultOption, backend)
+	if err != nil {
+		t.Fatalf("NewS3ClientWithAPI: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := cli.CreateBucket(ctx, "test", "glacier"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if err := cli.PutObject(ctx, "test", "glacier", "cold", []byte("frozen")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	backend.Archive("glacier", "cold")
+
+	if _, err := cli.GetObject(ctx, "test", "glacier", "cold"); !errors.Is(err, ErrObjectArchived) {
+		t.Fatalf("GetObject on archived object = %v, want ErrObjectArchived", err)
+	}
+
+	if err := cli.RestoreObject(ctx, "glacier", "cold", 0, ""); err == nil {
+		t.Fatal("RestoreObject with zero days succeeded, want error")
+	}
+	if err := cli.RestoreObject(ctx, "glacier", "cold", 1, "Instant"); err == nil {
+		t.Fatal("RestoreObject with bogus tier succeeded, want error")
+	}
+	if err := cli.RestoreObject(ctx, "glacier", "cold", 1, "Bulk"); err != nil {
+		t.Fatalf("RestoreObject: %v", err)
+	}
+	data, err := cli.GetObject(ctx, "test", "glacier", "cold")
+	if err != nil || string(data) != "frozen" {
+		t.Fatalf("GetObject after restore = %q, %v", data, err)
+	}
+}
+
+func TestCreateBucketIdempotentAndPopulated(t *testing.T) {
+	cli := fakeClient(t, "owned")
+	ctx := context.Background()
+
+	bkt, err := cli.CreateBucket(ctx, "test", "owned")
+	if err != nil {
+		t.Fatalf("CreateBucket on bucket we own: %v", err)
+	}
+	if bkt.Name != "owned" || bkt.Region != defaultOption.Region || bkt.CreatedAt.IsZero() {
+		t.Fatalf("Bucket = %+v, want name, region and creation time populated", bkt)
+	}
+}
+
+func TestListObjectParallel(t *testing.T) {
+	cli := fakeClient(t, "sharded")
+	ctx := context.Background()
+	keys := []string{"a/1", "a/2", "b/1", "c/1", "c/2", "c/3"}
+	for _, k := range keys {
+		if err := cli.PutObject(ctx, "test", "sharded", k, []byte("x")); err != nil {
+			t.Fatalf("PutObject %s: %v", k, err)
+		}
+	}
+
+	objs, err := cli.ListObjectParallel(ctx, "sharded", []string{"a/", "b/", "c/", "d/"})
+	if err != nil {
+		t.Fatalf("ListObjectParallel: %v", err)
+	}
+	if len(objs) != len(keys) {
+		t.Fatalf("listed %d objects, want %d", len(objs), len(keys))
+	}
+	for i, k := range keys {
+		if objs[i].Key != k {
+			t.Fatalf("objs[%d].Key = %q, want %q (merge not sorted?)", i, objs[i].Key, k)
+		}
+	}
+
+	// A missing bucket fails the whole listing, not just one shard.
+	if _, err := cli.ListObjectParallel(ctx, "no-such-bucket", []string{"a/", "b/"}); err == nil {
+		t.Fatal("ListObjectParallel on missing bucket succeeded, want error")
+	}
+}
+
+// headCountingAPI wraps the fake backend to count HeadObject calls.
+type headCountingAPI struct {
+	*s3test.Backend
+	heads int
+}
+
+func (h *headCountingAPI) HeadObject(ctx context.Context, in *s3v2.HeadObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.HeadObjectOutput, error) {
+	h.heads++
+	return h.Backend.HeadObject(ctx, in, optFns...)
+}
+
+// TestGetObjectNeedsNoHead pins the single-round-trip property of
+// GetObject: the response's ContentLength sizes the buffer, so no
+// preliminary HeadObject is issued.
+func TestGetObjectNeedsNoHead(t *testing.T) {
+	api := &headCountingAPI{Backend: s3test.New()}
+	cli, err := NewS3ClientWithAPI(defaultOption, api)
+	if err != nil {
+		t.Fatalf("NewS3ClientWithAPI: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := cli.CreateBucket(ctx, "test", "onetrip"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if err := cli.PutObject(ctx, "test", "onetrip", "k", []byte("body")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	api.heads = 0
+	data, err := cli.GetObject(ctx, "test", "onetrip", "k")
+	if err != nil || string(data) != "body" {
+		t.Fatalf("GetObject = %q, %v", data, err)
+	}
+	if api.heads != 0 {
+		t.Fatalf("GetObject issued %d HeadObject calls, want 0", api.heads)
+	}
+}
+
+func TestGetObjectToFile(t *testing.T) {
+	cli := fakeClient(t, "tofile")
+	ctx := context.Background()
+	body := bytes.Repeat([]byte("chunk"), 1024)
+	if err := cli.PutObject(ctx, "test", "tofile", "big", body); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "big")
+	n, err := cli.GetObjectToFile(ctx, "test", "tofile", "big", dst)
+	if err != nil {
+		t.Fatalf("GetObjectToFile: %v", err)
+	}
+	if n != int64(len(body)) {
+		t.Fatalf("wrote %d bytes, want %d", n, len(body))
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil || !bytes.Equal(data, body) {
+		t.Fatalf("file content mismatch: %d bytes, %v", len(data), err)
+	}
+
+	// A failed download must not leave a partial file behind.
+	if _, err := cli.GetObjectToFile(ctx, "test", "tofile", "missing", dst); err == nil {
+		t.Fatal("GetObjectToFile of a missing key succeeded")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("partial file left behind after failure: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testClient returns a client against the endpoint named by the
+// S3FS_TEST_ENDPOINT environment variable, or skips the test. These are
+// integration tests and need a reachable S3-compatible backend.
+func testClient(t *testing.T) *s3Client {
+	t.Helper()
+	endpoint := os.Getenv("S3FS_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3FS_TEST_ENDPOINT not set")
+	}
+	opt := defaultOption
+	opt.URL = endpoint
+	opt.AccessKey = os.Getenv("S3FS_TEST_ACCESS_KEY")
+	opt.SecretKey = os.Getenv("S3FS_TEST_SECRET_KEY")
+	cli, err := NewS3Client(opt)
+	if err != nil {
+		t.Fatalf("NewS3Client: %v", err)
+	}
+	return cli
+}
+
+// testBucket creates a scratch bucket and removes it when the test ends.
+func testBucket(t *testing.T, cli *s3Client) string {
+	t.Helper()
+	ctx := context.Background()
+	name := fmt.Sprintf("s3fs-test-%d", time.Now().UnixNano())
+	if _, err := cli.CreateBucket(ctx, "test", name); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cli.DeleteBucket(ctx, "test", name); err != nil {
+			t.Errorf("DeleteBucket: %v", err)
+		}
+	})
+	return name
+}
+
+// TestGetObjectCancelledContext verifies that downloads observe the
+// caller's context. GetObject used to pass context.TODO() to the
+// transfer manager, so cancellation was silently dropped.
+func TestGetObjectCancelledContext(t *testing.T) {
+	cli := testClient(t)
+	bucket := testBucket(t, cli)
+
+	if err := cli.PutObject(context.Background(), "test", bucket, "cancel-me", make([]byte, 1<<20)); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cli.DeleteObject(context.Background(), "test", bucket, "cancel-me"); err != nil {
+			t.Errorf("DeleteObject: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	_, err := cli.GetObject(ctx, "test", bucket, "cancel-me")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetObject with cancelled context: got %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("GetObject took %v to notice cancellation", elapsed)
+	}
+}
+
+func TestPutObjectIfNotExists(t *testing.T) {
+	cli := testClient(t)
+	bucket := testBucket(t, cli)
+	ctx := context.Background()
+
+	if err := cli.PutObject(ctx, "test", bucket, "exclusive", []byte("first"), IfNotExists()); err != nil {
+		t.Fatalf("initial conditional put: %v", err)
+	}
+	err := cli.PutObject(ctx, "test", bucket, "exclusive", []byte("second"), IfNotExists())
+	if !errors.Is(err, ErrObjectExists) {
+		t.Fatalf("second conditional put: got %v, want ErrObjectExists", err)
+	}
+	// The losing write must not have replaced the data.
+	data, err := cli.GetObject(ctx, "test", bucket, "exclusive")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	if string(data) != "first" {
+		t.Fatalf("object content = %q, want %q", data, "first")
+	}
+	if err := cli.DeleteObject(ctx, "test", bucket, "exclusive"); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CORSRule describes one CORS rule on a bucket, enough for web apps
+// uploading through presigned URLs.
+type CORSRule struct {
+	AllowedOrigins []string
+	// AllowedMethods are HTTP verbs from GET, PUT, POST, DELETE, HEAD.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// MaxAgeSeconds is how long browsers may cache the preflight answer.
+	MaxAgeSeconds int32
+}
+
+// corsMethods are the verbs S3 accepts in a CORS rule.
+var corsMethods = map[string]bool{
+	"GET": true, "PUT": true, "POST": true, "DELETE": true, "HEAD": true,
+}
+
+// validate rejects rules S3 would refuse, before the round-trip.
+func (r CORSRule) validate() error {
+	if len(r.AllowedOrigins) == 0 {
+		return fmt.Errorf("CORS rule needs at least one allowed origin")
+	}
+	if len(r.AllowedMethods) == 0 {
+		return fmt.Errorf("CORS rule needs at least one allowed method")
+	}
+	for _, m := range r.AllowedMethods {
+		if !corsMethods[m] {
+			return fmt.Errorf("invalid CORS method %q: must be GET, PUT, POST, DELETE or HEAD", m)
+		}
+	}
+	return nil
+}
+
+// PutBucketCORS replaces the bucket's CORS configuration with rules.
+func (c *s3Client) PutBucketCORS(ctx context.Context, bucket string, rules []CORSRule) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	sdkRules := make([]types.CORSRule, len(rules))
+	for i, r := range rules {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("put bucket cors %s: %w", bucket, err)
+		}
+		sdkRules[i] = types.CORSRule{
+			AllowedOrigins: r.AllowedOrigins,
+			AllowedMethods: r.AllowedMethods,
+			AllowedHeaders: r.AllowedHeaders,
+		}
+		if r.MaxAgeSeconds > 0 {
+			sdkRules[i].MaxAgeSeconds = aws.Int32(r.MaxAgeSeconds)
+		}
+	}
+	_, err := c.cli.PutBucketCors(ctx, &s3v2.PutBucketCorsInput{
+		Bucket:            aws.String(bucket),
+		CORSConfiguration: &types.CORSConfiguration{CORSRules: sdkRules},
+	})
+	if err != nil {
+		c.log.Errorf("put bucket cors %s error: %v", bucket, err)
+		return fmt.Errorf("put bucket cors %s: %w", bucket, classify(err))
+	}
+	return nil
+}
+
+// GetBucketCORS returns the bucket's CORS rules; a bucket without a
+// configuration returns an empty slice rather than an error.
+func (c *s3Client) GetBucketCORS(ctx context.Context, bucket string) ([]CORSRule, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetBucketCors(ctx, &s3v2.GetBucketCorsInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if apiErrorCode(err) == "NoSuchCORSConfiguration" {
+			return nil, nil
+		}
+		c.log.Errorf("get bucket cors %s error: %v", bucket, err)
+		return nil, fmt.Errorf("get bucket cors %s: %w", bucket, classify(err))
+	}
+	rules := make([]CORSRule, len(out.CORSRules))
+	for i, rule := range out.CORSRules {
+		rules[i] = CORSRule{
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			MaxAgeSeconds:  aws.ToInt32(rule.MaxAgeSeconds),
+		}
+	}
+	return rules, nil
+}
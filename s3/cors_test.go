@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBucketCORSRoundTrip(t *testing.T) {
+	cli := fakeClient(t, "cors")
+	ctx := context.Background()
+
+	rules, err := cli.GetBucketCORS(ctx, "cors")
+	if err != nil || rules != nil {
+		t.Fatalf("GetBucketCORS on fresh bucket = %v, %v; want nil, nil", rules, err)
+	}
+
+	want := []CORSRule{{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedHeaders: []string{"*"},
+		MaxAgeSeconds:  3600,
+	}}
+	if err := cli.PutBucketCORS(ctx, "cors", want); err != nil {
+		t.Fatalf("PutBucketCORS: %v", err)
+	}
+	got, err := cli.GetBucketCORS(ctx, "cors")
+	if err != nil {
+		t.Fatalf("GetBucketCORS: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rules round-tripped to %+v, want %+v", got, want)
+	}
+}
+
+func TestPutBucketCORSRejectsBadMethod(t *testing.T) {
+	cli := fakeClient(t, "cors-bad")
+	err := cli.PutBucketCORS(context.Background(), "cors-bad", []CORSRule{{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"PATCH"},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "PATCH") {
+		t.Fatalf("PutBucketCORS with PATCH: got %v, want invalid-method error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// endpointCooldown is how long an endpoint that failed at the connection
+// level is skipped before it is tried again.
+const endpointCooldown = 30 * time.Second
+
+// splitEndpoints breaks Option.URL into individual endpoint URLs. Both
+// comma and semicolon separate entries; connection strings parsed by
+// ParseOption have to use semicolons since commas separate its keys.
+func splitEndpoints(url string) []string {
+	var urls []string
+	for _, u := range strings.FieldsFunc(url, func(r rune) bool {
+		return r == ',' || r == ';'
+	}) {
+		u = strings.TrimSpace(u)
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// endpointPool hands out endpoint URLs round-robin, skipping ones that
+// recently failed so a downed gateway in an HA cluster isn't retried on
+// every request. When every endpoint is marked failed the rotation
+// continues as if none were, since refusing to answer helps nobody.
+type endpointPool struct {
+	mu       sync.Mutex
+	urls     []string
+	next     int
+	failedAt map[string]time.Time
+	cooldown time.Duration
+}
+
+func newEndpointPool(urls []string) *endpointPool {
+	return &endpointPool{
+		urls:     urls,
+		failedAt: make(map[string]time.Time),
+		cooldown: endpointCooldown,
+	}
+}
+
+// pick returns the next healthy endpoint in rotation.
+func (p *endpointPool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := 0; i < len(p.urls); i++ {
+		url := p.urls[p.next]
+		p.next = (p.next + 1) % len(p.urls)
+		if failed, ok := p.failedAt[url]; ok {
+			if time.Since(failed) < p.cooldown {
+				continue
+			}
+			delete(p.failedAt, url)
+		}
+		return url
+	}
+	// All endpoints are cooling down; give out the next one regardless.
+	url := p.urls[p.next]
+	p.next = (p.next + 1) % len(p.urls)
+	return url
+}
+
+// markFailed starts the cooldown for url.
+func (p *endpointPool) markFailed(url string) {
+	p.mu.Lock()
+	p.failedAt[url] = time.Now()
+	p.mu.Unlock()
+}
+
+// httpDoer is the SDK's HTTPClient contract.
+type httpDoer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// healthClient wraps the SDK's HTTP client to feed connection failures
+// back into the endpoint pool. Only transport-level errors mark an
+// endpoint unhealthy; HTTP error responses mean the gateway is up.
+type healthClient struct {
+	inner httpDoer
+	pool  *endpointPool
+}
+
+func (h *healthClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := h.inner.Do(req)
+	if err != nil {
+		h.pool.markFailed(req.URL.Scheme + "://" + req.URL.Host)
+	}
+	return resp, err
+}
+
+// CloseIdleConnections forwards to the wrapped client when it has idle
+// connections to close.
+func (h *healthClient) CloseIdleConnections() {
+	if c, ok := h.inner.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,52 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitEndpoints(t *testing.T) {
+	got := splitEndpoints("https://a:9000;https://b:9000, https://c:9000")
+	want := []string{"https://a:9000", "https://b:9000", "https://c:9000"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitEndpoints = %v, want %v", got, want)
+	}
+	if got := splitEndpoints(""); got != nil {
+		t.Fatalf("splitEndpoints(\"\") = %v, want nil", got)
+	}
+}
+
+func TestEndpointPoolFailover(t *testing.T) {
+	pool := newEndpointPool([]string{"a", "b", "c"})
+
+	if got := pool.pick(); got != "a" {
+		t.Fatalf("first pick = %q, want a", got)
+	}
+	if got := pool.pick(); got != "b" {
+		t.Fatalf("second pick = %q, want b", got)
+	}
+
+	// A failed endpoint is skipped until its cooldown passes.
+	pool.markFailed("c")
+	if got := pool.pick(); got != "a" {
+		t.Fatalf("pick after c failed = %q, want a", got)
+	}
+
+	// Once the cooldown is over the endpoint rejoins the rotation.
+	pool.failedAt["c"] = time.Now().Add(-2 * pool.cooldown)
+	if got := pool.pick(); got != "b" {
+		t.Fatalf("pick = %q, want b", got)
+	}
+	if got := pool.pick(); got != "c" {
+		t.Fatalf("pick after cooldown = %q, want c", got)
+	}
+
+	// With everything failed the rotation keeps going rather than hang.
+	for _, u := range []string{"a", "b", "c"} {
+		pool.markFailed(u)
+	}
+	if got := pool.pick(); got == "" {
+		t.Fatal("pick with all endpoints failed returned nothing")
+	}
+}
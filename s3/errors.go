@@ -0,0 +1,103 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/smithy-go"
+)
+
+// ErrObjectExists is returned by conditional writes (see IfNotExists) when
+// the target key already exists on the backend.
+var ErrObjectExists = errors.New("object already exists")
+
+// Typed classifications of backend failures. Client methods wrap the raw
+// smithy error with the matching sentinel so callers can branch with
+// errors.Is instead of string-matching service error codes.
+var (
+	ErrNoSuchBucket = errors.New("no such bucket")
+	ErrNoSuchKey    = errors.New("no such key")
+	ErrAccessDenied = errors.New("access denied")
+)
+
+// classify attaches the sentinel matching err's service error code, keeping
+// the original error in the chain. Errors with no mapped code pass through
+// unchanged.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch apiErrorCode(err) {
+	case "NoSuchBucket":
+		return fmt.Errorf("%w: %w", ErrNoSuchBucket, err)
+	case "NoSuchKey", "NotFound", "NoSuchVersion":
+		return fmt.Errorf("%w: %w", ErrNoSuchKey, err)
+	case "AccessDenied":
+		return fmt.Errorf("%w: %w", ErrAccessDenied, err)
+	case "InvalidObjectState":
+		return fmt.Errorf("%w: %w", ErrObjectArchived, err)
+	}
+	return err
+}
+
+// ErrObjectArchived is returned when reading an object whose storage
+// class (GLACIER, DEEP_ARCHIVE) keeps the data offline. Call
+// RestoreObject and retry once the restore completes; the mount reports
+// these reads as EAGAIN rather than a generic I/O error.
+var ErrObjectArchived = errors.New("object is archived")
+
+// ErrorPermissionDenied is returned by the client-side access gate when
+// the bucket policy doesn't grant the calling user the attempted
+// action. It is decided locally, before any request reaches the
+// backend; server-side rejections surface as ErrAccessDenied instead.
+var ErrorPermissionDenied = errors.New("permission denied")
+
+// ErrorObjectNotFound is the historical name for ErrNoSuchKey, kept so
+// callers written against the old API keep matching with errors.Is.
+var ErrorObjectNotFound = ErrNoSuchKey
+
+// ErrChecksumMismatch is returned when the backend reports BadDigest:
+// the body it received doesn't hash to the Content-MD5 we sent, meaning
+// the payload was corrupted in transit.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrorCantUploadEmptyFiles is returned for zero-byte uploads against
+// backends that reject an empty body. Plain S3 accepts them; some
+// compatible stores do not.
+var ErrorCantUploadEmptyFiles = errors.New("backend can't store empty files")
+
+// apiErrorCode extracts the service error code from err, or "" if err is not
+// an API error.
+func apiErrorCode(err error) string {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		return ae.ErrorCode()
+	}
+	return ""
+}
+
+// isNotModified reports whether err is the 304 a conditional GET with a
+// matching If-None-Match comes back with.
+func isNotModified(err error) bool {
+	return apiErrorCode(err) == "NotModified"
+}
+
+// isPreconditionFailed reports whether err is the 412 the backend returns
+// when an If-None-Match conditional write loses.
+func isPreconditionFailed(err error) bool {
+	return apiErrorCode(err) == "PreconditionFailed"
+}
+
+// isNotFound reports whether err means the key or bucket does not exist.
+// HeadObject surfaces a bare 404 as "NotFound" while GetObject reports
+// "NoSuchKey". It accepts both raw and classified errors.
+func isNotFound(err error) bool {
+	if errors.Is(err, ErrNoSuchKey) || errors.Is(err, ErrNoSuchBucket) {
+		return true
+	}
+	switch apiErrorCode(err) {
+	case "NotFound", "NoSuchKey", "NoSuchBucket":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,865 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// errnoFromS3 translates a classified client error into the errno the
+// kernel should see. fs.ToErrno only understands syscall errors, so
+// without this every backend failure surfaced as EIO.
+func errnoFromS3(err error) syscall.Errno {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrNoSuchKey), errors.Is(err, ErrNoSuchBucket):
+		return syscall.ENOENT
+	case errors.Is(err, ErrAccessDenied), errors.Is(err, ErrorPermissionDenied):
+		return syscall.EACCES
+	case errors.Is(err, ErrObjectArchived):
+		// Not gone and not forbidden: the data comes back after a
+		// restore, so tell the caller to try again later.
+		return syscall.EAGAIN
+	case errors.Is(err, context.DeadlineExceeded):
+		return syscall.ETIMEDOUT
+	case errors.Is(err, context.Canceled):
+		return syscall.EINTR
+	}
+	return syscall.EIO
+}
+
+// s3Root is a directory inode backed by an S3 prefix. The mount root is an
+// s3Root with an empty prefix; subdirectories are further s3Roots sharing
+// the same client. Directory contents are listed lazily on Readdir/Lookup
+// and cached for the configured attr timeout, so a bucket of any size can be
+// mounted without walking it up front. That lazy scheme is also why there
+// is no OnAdd: mounting only establishes this root node, and the first
+// listing happens when the kernel asks for it.
+type s3Root struct {
+	fs.Inode
+
+	cli    *s3Client
+	bucket string
+	// prefix is the key prefix of this directory: empty at the root,
+	// otherwise ending in "/".
+	prefix string
+
+	mu       sync.Mutex
+	children map[string]Object
+	listTime time.Time
+}
+
+var _ = (fs.NodeGetattrer)((*s3Root)(nil))
+var _ = (fs.NodeStatfser)((*s3Root)(nil))
+var _ = (fs.NodeReaddirer)((*s3Root)(nil))
+var _ = (fs.NodeLookuper)((*s3Root)(nil))
+var _ = (fs.NodeMkdirer)((*s3Root)(nil))
+var _ = (fs.NodeUnlinker)((*s3Root)(nil))
+var _ = (fs.NodeCreater)((*s3Root)(nil))
+var _ = (fs.NodeRmdirer)((*s3Root)(nil))
+var _ = (fs.NodeRenamer)((*s3Root)(nil))
+
+func (sr *s3Root) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | sr.cli.opt.dirMode()
+	out.Uid = sr.cli.opt.ownerUID(0)
+	out.Gid = sr.cli.opt.ownerGID(0)
+	return 0
+}
+
+// statfsBlockSize is the block size reported to statfs callers. Nothing
+// on the backend blocks at this granularity; df just needs a unit.
+const statfsBlockSize = 4096
+
+// Statfs reports plausible filesystem statistics: S3 has no real size
+// limit, so free space defaults to 1PiB unless the FreeSpace option asks
+// for a specific budget. The file count is taken from the cached listing
+// of this directory — cheap, and close enough for the tools that look.
+func (sr *s3Root) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	free := uint64(1 << 50)
+	if sr.cli.opt.FreeSpace > 0 {
+		free = uint64(sr.cli.opt.FreeSpace)
+	}
+	blocks := free / statfsBlockSize
+	out.Bsize = statfsBlockSize
+	out.Frsize = statfsBlockSize
+	out.Blocks = blocks
+	out.Bfree = blocks
+	out.Bavail = blocks
+	sr.mu.Lock()
+	out.Files = uint64(len(sr.children))
+	sr.mu.Unlock()
+	out.Ffree = 1 << 30
+	out.NameLen = 1024
+	return 0
+}
+
+// listChildren returns the child objects of this prefix, re-listing from S3
+// at most once per attr timeout so repeated ls calls don't hammer the
+// backend.
+func (sr *s3Root) listChildren(ctx context.Context) (map[string]Object, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if sr.children != nil && time.Since(sr.listTime) < sr.cli.opt.AttrTimeout {
+		return sr.children, nil
+	}
+	objs, err := sr.cli.ListObject(ctx, sr.cli.opt.UserID, sr.bucket, sr.prefix)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[string]Object, len(objs))
+	for _, obj := range objs {
+		name := strings.TrimSuffix(strings.TrimPrefix(obj.Key, sr.prefix), "/")
+		if name == "" || strings.Contains(name, "/") {
+			// The marker object for this directory itself, or a key that
+			// doesn't belong at this level.
+			continue
+		}
+		children[name] = obj
+	}
+	sr.children = children
+	sr.listTime = time.Now()
+	return children, nil
+}
+
+// invalidate drops the cached listing so the next access re-lists.
+func (sr *s3Root) invalidate() {
+	sr.mu.Lock()
+	sr.children = nil
+	sr.mu.Unlock()
+}
+
+func (sr *s3Root) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	children, err := sr.listChildren(ctx)
+	if err != nil {
+		return nil, errnoFromS3(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(children))
+	for name, obj := range children {
+		mode := uint32(fuse.S_IFREG)
+		if obj.IsDir {
+			mode = fuse.S_IFDIR
+		}
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: mode})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (sr *s3Root) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if name == snapshotsDirName {
+		child := &s3SnapshotsDir{cli: sr.cli, bucket: sr.bucket, prefix: sr.prefix}
+		return sr.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+	}
+	children, err := sr.listChildren(ctx)
+	if err != nil {
+		return nil, errnoFromS3(err)
+	}
+	obj, ok := children[name]
+	if !ok {
+		// Not in the cached listing — it may have been created by another
+		// client since. Probe the exact key first, then for an implicit
+		// directory, before giving up.
+		found, errno := sr.lookupUncached(ctx, name)
+		if errno != 0 {
+			return nil, errno
+		}
+		obj = *found
+		sr.mu.Lock()
+		if sr.children != nil {
+			sr.children[name] = obj
+		}
+		sr.mu.Unlock()
+	}
+	return sr.newChildInode(ctx, obj), 0
+}
+
+// lookupUncached checks the backend for a key or implicit directory that
+// the cached listing doesn't know about.
+func (sr *s3Root) lookupUncached(ctx context.Context, name string) (*Object, syscall.Errno) {
+	key := sr.prefix + name
+	head, err := sr.cli.HeadObject(ctx, sr.cli.opt.UserID, sr.bucket, key)
+	if err == nil {
+		head.Key = key
+		return head, 0
+	}
+	if !isNotFound(err) {
+		return nil, errnoFromS3(err)
+	}
+	// No object with the exact key; child keys under name/ make it an
+	// implicit directory.
+	objs, err := sr.cli.ListObject(ctx, sr.cli.opt.UserID, sr.bucket, key+"/")
+	if err != nil {
+		return nil, errnoFromS3(err)
+	}
+	if len(objs) == 0 {
+		return nil, syscall.ENOENT
+	}
+	return &Object{Key: key + "/", IsDir: true}, 0
+}
+
+// Mkdir creates a zero-byte marker object with a trailing slash key, which
+// is the usual convention for empty directories on stores that only have
+// flat keys. Directories that already exist implicitly because child keys
+// exist are fine too: re-putting the marker is idempotent and Readdir folds
+// both cases into one directory entry.
+func (sr *s3Root) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if sr.cli.opt.ReadOnly {
+		return nil, syscall.EROFS
+	}
+	key := sr.prefix + name + "/"
+	if err := sr.cli.PutObject(ctx, sr.cli.opt.UserID, sr.bucket, key, nil); err != nil {
+		return nil, errnoFromS3(err)
+	}
+	sr.mu.Lock()
+	if sr.children != nil {
+		sr.children[name] = Object{Key: key, IsDir: true}
+	}
+	sr.mu.Unlock()
+	child := &s3Root{cli: sr.cli, bucket: sr.bucket, prefix: key}
+	return sr.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+// Unlink deletes the backing object of a child file and drops its inode.
+func (sr *s3Root) Unlink(ctx context.Context, name string) syscall.Errno {
+	if sr.cli.opt.ReadOnly {
+		return syscall.EROFS
+	}
+	key := sr.prefix + name
+	if _, err := sr.cli.HeadObject(ctx, sr.cli.opt.UserID, sr.bucket, key); err != nil {
+		if isNotFound(err) {
+			return syscall.ENOENT
+		}
+		return errnoFromS3(err)
+	}
+	if err := sr.cli.DeleteObject(ctx, sr.cli.opt.UserID, sr.bucket, key); err != nil {
+		return errnoFromS3(err)
+	}
+	sr.mu.Lock()
+	delete(sr.children, name)
+	sr.mu.Unlock()
+	sr.RmChild(name)
+	return 0
+}
+
+// Rmdir removes a child directory by purging everything under its prefix,
+// marker object included. The bucket itself is never touched, and
+// removing a directory that still has content works, which is what makes
+// rm -rf on the mount effective.
+func (sr *s3Root) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if sr.cli.opt.ReadOnly {
+		return syscall.EROFS
+	}
+	prefix := sr.prefix + name + "/"
+	if err := sr.cli.Purge(ctx, sr.cli.opt.UserID, sr.bucket, prefix); err != nil {
+		return errnoFromS3(err)
+	}
+	sr.mu.Lock()
+	delete(sr.children, name)
+	sr.mu.Unlock()
+	return 0
+}
+
+// Rename moves a child to newParent under newName. Files are a single
+// server-side copy plus delete. Directories have no backend identity of
+// their own, so the whole subtree is walked: every key under the old
+// prefix is copied to the new prefix preserving its relative path, then
+// the old keys are removed in DeleteObjects batches. Renaming a
+// directory into its own subtree is rejected, as is a rename across
+// buckets — the kernel falls back to copy+unlink for EXDEV.
+func (sr *s3Root) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if sr.cli.opt.ReadOnly {
+		return syscall.EROFS
+	}
+	np, ok := newParent.(*s3Root)
+	if !ok || np.bucket != sr.bucket {
+		return syscall.EXDEV
+	}
+	obj, errno := sr.childObject(ctx, name)
+	if errno != 0 {
+		return errno
+	}
+	userID := sr.cli.opt.UserID
+	if obj.IsDir {
+		oldPrefix := sr.prefix + name + "/"
+		newPrefix := np.prefix + newName + "/"
+		if oldPrefix == newPrefix || strings.HasPrefix(newPrefix, oldPrefix) {
+			return syscall.EINVAL
+		}
+		batch := make([]string, 0, deleteBatchSize)
+		err := sr.cli.ListObjectRecursiveFunc(ctx, userID, sr.bucket, oldPrefix, func(o Object) error {
+			dst := newPrefix + strings.TrimPrefix(o.Key, oldPrefix)
+			if err := sr.cli.CopyObject(ctx, userID, sr.bucket, o.Key, dst); err != nil {
+				return err
+			}
+			batch = append(batch, o.Key)
+			if len(batch) == deleteBatchSize {
+				if err := sr.cli.deleteBatch(ctx, sr.bucket, batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+			return nil
+		})
+		if err == nil && len(batch) > 0 {
+			err = sr.cli.deleteBatch(ctx, sr.bucket, batch)
+		}
+		if err != nil {
+			return errnoFromS3(err)
+		}
+	} else {
+		oldKey := sr.prefix + name
+		newKey := np.prefix + newName
+		if oldKey != newKey {
+			if err := sr.cli.CopyObject(ctx, userID, sr.bucket, oldKey, newKey); err != nil {
+				return errnoFromS3(err)
+			}
+			if err := sr.cli.DeleteObject(ctx, userID, sr.bucket, oldKey); err != nil {
+				return errnoFromS3(err)
+			}
+		}
+	}
+	sr.invalidate()
+	np.invalidate()
+	return 0
+}
+
+// childObject resolves name to its Object, consulting the cached listing
+// first and the backend second.
+func (sr *s3Root) childObject(ctx context.Context, name string) (Object, syscall.Errno) {
+	children, err := sr.listChildren(ctx)
+	if err != nil {
+		return Object{}, errnoFromS3(err)
+	}
+	if obj, ok := children[name]; ok {
+		return obj, 0
+	}
+	found, errno := sr.lookupUncached(ctx, name)
+	if errno != 0 {
+		return Object{}, errno
+	}
+	return *found, 0
+}
+
+// Create makes a new file under this directory. The key is claimed on the
+// backend right away with a zero-byte put — conditionally for O_EXCL so
+// concurrent creators race on S3 rather than in memory — and the handle
+// buffers writes until release uploads the final content. Zero-byte files
+// (touch) therefore exist on the backend even if nothing is ever written;
+// see ErrorCantUploadEmptyFiles for backends that reject that.
+func (sr *s3Root) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	if sr.cli.opt.ReadOnly {
+		return nil, nil, 0, syscall.EROFS
+	}
+	key := sr.prefix + name
+	var opts []PutOption
+	if flags&syscall.O_EXCL != 0 {
+		opts = append(opts, IfNotExists())
+	}
+	if err := sr.cli.PutObject(ctx, sr.cli.opt.UserID, sr.bucket, key, []byte{}, opts...); err != nil {
+		if errors.Is(err, ErrObjectExists) {
+			return nil, nil, 0, syscall.EEXIST
+		}
+		// With AllowEmptyFiles off the zero-byte claim is rejected
+		// client-side; the create still succeeds and the key reaches the
+		// backend on the first flush that has content. O_EXCL loses its
+		// S3-side race protection in that mode, nothing more.
+		if !errors.Is(err, ErrorCantUploadEmptyFiles) {
+			return nil, nil, 0, errnoFromS3(err)
+		}
+	}
+	child := &s3File{
+		cli:    sr.cli,
+		bucket: sr.bucket,
+		key:    key,
+		mtime:  time.Now(),
+	}
+	sr.mu.Lock()
+	if sr.children != nil {
+		sr.children[name] = Object{Key: key, LastModified: child.mtime}
+	}
+	sr.mu.Unlock()
+	h := &s3FileHandle{data: []byte{}}
+	return sr.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFREG}), h, 0, 0
+}
+
+// newChildInode wraps obj in a directory or file inode below this one.
+func (sr *s3Root) newChildInode(ctx context.Context, obj Object) *fs.Inode {
+	if obj.IsDir {
+		child := &s3Root{cli: sr.cli, bucket: sr.bucket, prefix: obj.Key}
+		return sr.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFDIR})
+	}
+	child := &s3File{
+		cli:    sr.cli,
+		bucket: sr.bucket,
+		key:    obj.Key,
+		size:   obj.Size,
+		mtime:  obj.LastModified,
+	}
+	return sr.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFREG})
+}
+
+// s3BucketsRoot presents every bucket visible to the credentials as a
+// top-level directory, descending lazily into each.
+type s3BucketsRoot struct {
+	fs.Inode
+
+	cli *s3Client
+}
+
+var _ = (fs.NodeGetattrer)((*s3BucketsRoot)(nil))
+var _ = (fs.NodeReaddirer)((*s3BucketsRoot)(nil))
+var _ = (fs.NodeLookuper)((*s3BucketsRoot)(nil))
+
+func (br *s3BucketsRoot) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | br.cli.opt.dirMode()
+	out.Uid = br.cli.opt.ownerUID(0)
+	out.Gid = br.cli.opt.ownerGID(0)
+	return 0
+}
+
+func (br *s3BucketsRoot) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	buckets, err := br.cli.ListBuckets(ctx, br.cli.opt.UserID)
+	if err != nil {
+		// Some credentials can use specific buckets but not enumerate
+		// them all; present an empty root and let Lookup find the ones
+		// the user names explicitly.
+		if apiErrorCode(err) == "AccessDenied" {
+			return fs.NewListDirStream(nil), 0
+		}
+		return nil, errnoFromS3(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(buckets))
+	for _, b := range buckets {
+		entries = append(entries, fuse.DirEntry{Name: b.Name, Mode: fuse.S_IFDIR})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (br *s3BucketsRoot) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if _, err := br.cli.cli.HeadBucket(ctx, &s3v2.HeadBucketInput{Bucket: &name}); err != nil {
+		if isNotFound(err) {
+			return nil, syscall.ENOENT
+		}
+		return nil, errnoFromS3(err)
+	}
+	child := &s3Root{cli: br.cli, bucket: name}
+	return br.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+// NewS3FileSystem returns the root inode for the named target,
+// connecting with the comma separated connection args accepted by
+// ParseOption. The name is "bucket" to mount a whole bucket or
+// "bucket/some/dir" to mount only that subpath: listings and new keys
+// are scoped under the prefix, which never shows up in the presented
+// paths. An empty name mounts all visible buckets as top-level
+// directories. The result can be mounted with fs.Mount; pair it with
+// MountOptions so the kernel options agree with the node behavior.
+//
+// This is the canonical constructor; NewS3Tree and NewArchiveFileSystem
+// are historical names for it.
+func NewS3FileSystem(name, args string) (fs.InodeEmbedder, error) {
+	opt := ParseOption(args)
+	cli, err := NewS3Client(opt)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return &s3BucketsRoot{cli: cli}, nil
+	}
+	bucket, prefix, _ := strings.Cut(name, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		// The root prefix follows the same convention as every other
+		// directory: empty or ending in "/".
+		prefix += "/"
+	}
+	return &s3Root{cli: cli, bucket: bucket, prefix: prefix}, nil
+}
+
+// Close releases the underlying client. Call it after the FUSE server
+// has finished serving (typically right after server.Wait returns) so
+// staged write-back data reaches the backend before the process exits.
+func (sr *s3Root) Close() error {
+	return sr.cli.Close()
+}
+
+// Close releases the underlying client; see (*s3Root).Close.
+func (br *s3BucketsRoot) Close() error {
+	return br.cli.Close()
+}
+
+// MountOptions returns the go-fuse mount options matching opt, so the
+// kernel sees a mount that agrees with how the nodes behave — notably
+// the ro option for read-only mounts.
+func MountOptions(opt Option) *fs.Options {
+	fsOpts := &fs.Options{}
+	fsOpts.MountOptions.Name = "s3fs"
+	if opt.ReadOnly {
+		fsOpts.MountOptions.Options = append(fsOpts.MountOptions.Options, "ro")
+	}
+	return fsOpts
+}
+
+// NewS3Tree is the historical name of NewS3FileSystem, kept for callers
+// that predate the rename.
+func NewS3Tree(name, args string) (fs.InodeEmbedder, error) {
+	return NewS3FileSystem(name, args)
+}
+
+// NewArchiveFileSystem likewise predates the rename to NewS3FileSystem.
+func NewArchiveFileSystem(name, args string) (fs.InodeEmbedder, error) {
+	return NewS3FileSystem(name, args)
+}
+
+// s3File is a file inode backed by a single S3 object. cli is the same
+// *s3Client the whole tree shares — nodes never copy the client, since
+// it carries mutexes, caches and the write-back workers.
+type s3File struct {
+	fs.Inode
+
+	cli    *s3Client
+	bucket string
+	key    string
+	size   int64
+	mtime  time.Time
+	// mode is the permission bits persisted as user metadata, or 0 when
+	// the object carries none.
+	mode uint32
+	uid  uint32
+	gid  uint32
+
+	attrMu     sync.Mutex
+	attrLoaded bool
+}
+
+// metadata renders the POSIX attributes that survive as S3 user metadata.
+func (f *s3File) metadata() map[string]string {
+	md := map[string]string{
+		"mtime": strconv.FormatInt(f.mtime.Unix(), 10),
+	}
+	if f.mode != 0 {
+		md["mode"] = strconv.FormatUint(uint64(f.mode), 8)
+	}
+	if f.uid != 0 || f.gid != 0 {
+		md["uid"] = strconv.FormatUint(uint64(f.uid), 10)
+		md["gid"] = strconv.FormatUint(uint64(f.gid), 10)
+	}
+	return md
+}
+
+// applyMetadata is the inverse of metadata: it restores the POSIX
+// attributes stored on the object, ignoring anything malformed.
+func (f *s3File) applyMetadata(md map[string]string) {
+	if v, ok := md["mode"]; ok {
+		if m, err := strconv.ParseUint(v, 8, 32); err == nil {
+			f.mode = uint32(m) & 07777
+		}
+	}
+	if v, ok := md["mtime"]; ok {
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+			f.mtime = time.Unix(sec, 0)
+		}
+	}
+	if v, ok := md["uid"]; ok {
+		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
+			f.uid = uint32(id)
+		}
+	}
+	if v, ok := md["gid"]; ok {
+		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
+			f.gid = uint32(id)
+		}
+	}
+}
+
+// loadAttrs fetches the object's metadata once, the first time attributes
+// are asked for. Failures are ignored: the listing-derived defaults are
+// still serviceable.
+func (f *s3File) loadAttrs(ctx context.Context) {
+	f.attrMu.Lock()
+	defer f.attrMu.Unlock()
+	if f.attrLoaded {
+		return
+	}
+	f.attrLoaded = true
+	obj, err := f.cli.HeadObject(ctx, f.cli.opt.UserID, f.bucket, f.key)
+	if err != nil {
+		return
+	}
+	f.size = obj.Size
+	if !obj.LastModified.IsZero() {
+		f.mtime = obj.LastModified
+	}
+	f.applyMetadata(obj.Metadata)
+}
+
+var _ = (fs.NodeGetattrer)((*s3File)(nil))
+var _ = (fs.NodeOpener)((*s3File)(nil))
+var _ = (fs.NodeReader)((*s3File)(nil))
+var _ = (fs.NodeWriter)((*s3File)(nil))
+var _ = (fs.NodeFlusher)((*s3File)(nil))
+var _ = (fs.NodeFsyncer)((*s3File)(nil))
+var _ = (fs.NodeReleaser)((*s3File)(nil))
+var _ = (fs.NodeSetattrer)((*s3File)(nil))
+
+func (f *s3File) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	f.loadAttrs(ctx)
+	mode := f.mode
+	if mode == 0 {
+		mode = f.cli.opt.fileMode()
+	}
+	out.Mode = fuse.S_IFREG | mode
+	out.Size = uint64(f.size)
+	out.Uid = f.cli.opt.ownerUID(f.uid)
+	out.Gid = f.cli.opt.ownerGID(f.gid)
+	mtime := f.mtime
+	if mtime.IsZero() {
+		mtime = time.Now()
+	}
+	out.SetTimes(nil, &mtime, nil)
+	return 0
+}
+
+// Setattr handles truncate, chmod and utimes. Size changes cut the write
+// buffer when a handle is open; otherwise the object is rewritten at the
+// new length. S3 has no native file attributes, so mode and mtime are
+// persisted as user metadata on the next upload — immediately, via a
+// rewrite, when no write is pending.
+func (f *s3File) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if f.cli.opt.ReadOnly {
+		return syscall.EROFS
+	}
+	h, _ := fh.(*s3FileHandle)
+	if sz, ok := in.GetSize(); ok {
+		if h != nil {
+			h.mu.Lock()
+			if sz <= uint64(len(h.data)) {
+				h.data = h.data[:sz]
+			} else {
+				grown := make([]byte, sz)
+				copy(grown, h.data)
+				h.data = grown
+			}
+			h.dirty = true
+			h.mu.Unlock()
+		} else {
+			data := []byte{}
+			if sz > 0 {
+				var err error
+				data, err = f.cli.GetObject(ctx, f.cli.opt.UserID, f.bucket, f.key)
+				if err != nil {
+					return errnoFromS3(err)
+				}
+				if sz < uint64(len(data)) {
+					data = data[:sz]
+				} else if sz > uint64(len(data)) {
+					grown := make([]byte, sz)
+					copy(grown, data)
+					data = grown
+				}
+			}
+			if err := f.cli.PutObject(ctx, f.cli.opt.UserID, f.bucket, f.key, data, WithMetadata(f.metadata())); err != nil {
+				return errnoFromS3(err)
+			}
+		}
+		f.size = int64(sz)
+	}
+	attrChanged := false
+	if m, ok := in.GetMode(); ok {
+		f.mode = m & 07777
+		attrChanged = true
+	}
+	if mt, ok := in.GetMTime(); ok {
+		f.mtime = mt
+		attrChanged = true
+	}
+	if id, ok := in.GetUID(); ok {
+		f.uid = id
+		attrChanged = true
+	}
+	if id, ok := in.GetGID(); ok {
+		f.gid = id
+		attrChanged = true
+	}
+	if attrChanged && (h == nil || !h.isDirty()) {
+		// Nothing pending that would carry the metadata along; a self-copy
+		// rewrites the attributes on the backend without moving the data.
+		if err := f.cli.UpdateObjectMetadata(ctx, f.cli.opt.UserID, f.bucket, f.key, "", f.metadata()); err != nil {
+			return errnoFromS3(err)
+		}
+	}
+	return f.Getattr(ctx, fh, out)
+}
+
+// s3FileHandle holds the object body for the lifetime of one open. Writes
+// mark it dirty; the content is uploaded when the handle is released.
+type s3FileHandle struct {
+	mu    sync.Mutex
+	data  []byte
+	dirty bool
+	// append forces every write to the end of the buffer, for handles
+	// opened with O_APPEND.
+	append bool
+}
+
+func (h *s3FileHandle) isDirty() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.dirty
+}
+
+func (f *s3File) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if f.cli.opt.ReadOnly && flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	h := &s3FileHandle{append: flags&syscall.O_APPEND != 0}
+	switch {
+	case flags&syscall.O_TRUNC != 0:
+		// Start from an empty dirty buffer so the truncation reaches the
+		// backend on flush even if nothing is ever written.
+		h.data = []byte{}
+		h.dirty = true
+		f.size = 0
+	case flags&syscall.O_ACCMODE == syscall.O_WRONLY && !h.append:
+		// Write-only rewrite: the old content is never read through this
+		// handle, so skip the download and build the object from scratch.
+		h.data = []byte{}
+	default:
+		data, err := f.cli.getObjectCached(ctx, f.cli.opt.UserID, f.bucket, f.key)
+		if err != nil {
+			return nil, 0, errnoFromS3(err)
+		}
+		h.data = data
+	}
+	return h, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (f *s3File) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h, ok := fh.(*s3FileHandle)
+	if !ok {
+		return nil, syscall.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if off >= int64(len(h.data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := off + int64(len(dest))
+	if end > int64(len(h.data)) {
+		end = int64(len(h.data))
+	}
+	return fuse.ReadResultData(h.data[off:end]), 0
+}
+
+func (f *s3File) Write(ctx context.Context, fh fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	if f.cli.opt.ReadOnly {
+		return 0, syscall.EROFS
+	}
+	h, ok := fh.(*s3FileHandle)
+	if !ok {
+		return 0, syscall.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.append {
+		off = int64(len(h.data))
+	}
+	end := off + int64(len(data))
+	if end > int64(len(h.data)) {
+		grown := make([]byte, end)
+		copy(grown, h.data)
+		h.data = grown
+	}
+	copy(h.data[off:end], data)
+	h.dirty = true
+	f.size = int64(len(h.data))
+	return uint32(len(data)), 0
+}
+
+// Flush pushes the buffered content to the backend if any write dirtied
+// it. The kernel sends FLUSH on every close(), so upload failures here
+// reach the application as a close error instead of vanishing. A handle
+// that was only read from flushes as a no-op.
+func (f *s3File) Flush(ctx context.Context, fh fs.FileHandle) syscall.Errno {
+	h, ok := fh.(*s3FileHandle)
+	if !ok {
+		return syscall.EBADF
+	}
+	return f.flush(ctx, h)
+}
+
+// flush uploads h's buffer when dirty, directly or via the writeback
+// queue. The buffer is copied before enqueueing because the handle may
+// still be written through afterwards.
+func (f *s3File) flush(ctx context.Context, h *s3FileHandle) syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.dirty {
+		return 0
+	}
+	f.mtime = time.Now()
+	if f.cli.wb != nil {
+		data := make([]byte, len(h.data))
+		copy(data, h.data)
+		f.cli.wb.enqueue(f.bucket, f.key, data, f.metadata())
+		h.dirty = false
+		return 0
+	}
+	if err := f.cli.PutObject(ctx, f.cli.opt.UserID, f.bucket, f.key, h.data, WithMetadata(f.metadata())); err != nil {
+		// An empty buffer rejected client-side is not a close error: the
+		// file simply stays absent from the backend until it has content.
+		if !errors.Is(err, ErrorCantUploadEmptyFiles) {
+			return errnoFromS3(err)
+		}
+	}
+	h.dirty = false
+	return 0
+}
+
+// Fsync uploads the dirty buffer synchronously and, when write-back is
+// enabled, additionally drains the queue so earlier staged uploads of
+// this or other files are on the backend before fsync returns. Only
+// after that may callers treat the data as durable.
+func (f *s3File) Fsync(ctx context.Context, fh fs.FileHandle, flags uint32) syscall.Errno {
+	h, ok := fh.(*s3FileHandle)
+	if !ok {
+		return syscall.EBADF
+	}
+	h.mu.Lock()
+	if h.dirty {
+		f.mtime = time.Now()
+		if err := f.cli.PutObject(ctx, f.cli.opt.UserID, f.bucket, f.key, h.data, WithMetadata(f.metadata())); err != nil {
+			h.mu.Unlock()
+			return errnoFromS3(err)
+		}
+		h.dirty = false
+	}
+	h.mu.Unlock()
+	if err := f.cli.Sync(); err != nil {
+		return errnoFromS3(err)
+	}
+	return 0
+}
+
+// Release drops the handle's buffer. Dirty data is normally gone by now
+// — the kernel flushes on close — but a handle released without a flush
+// (an aborted process, for instance) gets one last push so writes are
+// not silently lost.
+func (f *s3File) Release(ctx context.Context, fh fs.FileHandle) syscall.Errno {
+	h, ok := fh.(*s3FileHandle)
+	if !ok {
+		return syscall.EBADF
+	}
+	errno := f.flush(ctx, h)
+	h.mu.Lock()
+	h.data = nil
+	h.mu.Unlock()
+	return errno
+}
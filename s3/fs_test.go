@@ -0,0 +1,259 @@
+package s3
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	"github.com/ThierryZhou/go-s3fs/s3/s3test"
+)
+
+// TestPrefixRootScopesListing mounts a subpath of a bucket and checks
+// that listings are confined to the prefix with the prefix itself
+// stripped from the presented names.
+func TestPrefixRootScopesListing(t *testing.T) {
+	cli := fakeClient(t, "scoped")
+	ctx := context.Background()
+	for _, key := range []string{"mnt/a", "mnt/sub/b", "other/c"} {
+		if err := cli.PutObject(ctx, "test", "scoped", key, []byte(key)); err != nil {
+			t.Fatalf("PutObject %s: %v", key, err)
+		}
+	}
+
+	root := &s3Root{cli: cli, bucket: "scoped", prefix: "mnt/"}
+	children, err := root.listChildren(ctx)
+	if err != nil {
+		t.Fatalf("listChildren: %v", err)
+	}
+	if len(children) != 2 {
+		t.Fatalf("children = %v, want a and sub", children)
+	}
+	if obj, ok := children["a"]; !ok || obj.IsDir || obj.Key != "mnt/a" {
+		t.Fatalf("child a = %+v, want file mnt/a", children["a"])
+	}
+	if obj, ok := children["sub"]; !ok || !obj.IsDir || obj.Key != "mnt/sub/" {
+		t.Fatalf("child sub = %+v, want dir mnt/sub/", children["sub"])
+	}
+}
+
+// TestRenameDirectoryMovesSubtree renames a directory and checks every
+// key under the old prefix lands under the new one with its relative
+// path intact, and that renaming a directory into itself is refused.
+func TestRenameDirectoryMovesSubtree(t *testing.T) {
+	cli := fakeClient(t, "mvdir")
+	ctx := context.Background()
+	for _, key := range []string{"src/a", "src/deep/b", "src/deep/er/c"} {
+		if err := cli.PutObject(ctx, "test", "mvdir", key, []byte(key)); err != nil {
+			t.Fatalf("PutObject %s: %v", key, err)
+		}
+	}
+
+	root := &s3Root{cli: cli, bucket: "mvdir"}
+	if errno := root.Rename(ctx, "src", root, "src", 0); errno != syscall.EINVAL {
+		t.Fatalf("rename dir onto itself = %v, want EINVAL", errno)
+	}
+	if errno := root.Rename(ctx, "src", root, "dst", 0); errno != 0 {
+		t.Fatalf("Rename: %v", errno)
+	}
+
+	objs, err := cli.ListObjectRecursive(ctx, "test", "mvdir", "")
+	if err != nil {
+		t.Fatalf("ListObjectRecursive: %v", err)
+	}
+	want := map[string]bool{"dst/a": true, "dst/deep/b": true, "dst/deep/er/c": true}
+	for _, obj := range objs {
+		if !want[obj.Key] {
+			t.Fatalf("unexpected key %q after rename", obj.Key)
+		}
+		delete(want, obj.Key)
+	}
+	if len(want) != 0 {
+		t.Fatalf("keys missing after rename: %v", want)
+	}
+}
+
+// openFileNode puts content at key and returns an s3File node for it.
+func openFileNode(t *testing.T, cli *s3Client, bucket, key, content string) *s3File {
+	t.Helper()
+	if err := cli.PutObject(context.Background(), "test", bucket, key, []byte(content)); err != nil {
+		t.Fatalf("PutObject %s: %v", key, err)
+	}
+	return &s3File{cli: cli, bucket: bucket, key: key, size: int64(len(content))}
+}
+
+func TestOpenTruncDiscardsContent(t *testing.T) {
+	cli := fakeClient(t, "flags")
+	ctx := context.Background()
+	f := openFileNode(t, cli, "flags", "t", "old content")
+
+	fh, _, errno := f.Open(ctx, syscall.O_WRONLY|syscall.O_TRUNC)
+	if errno != 0 {
+		t.Fatalf("Open: %v", errno)
+	}
+	// No writes: the truncation alone must reach the backend.
+	if errno := f.Flush(ctx, fh); errno != 0 {
+		t.Fatalf("Flush: %v", errno)
+	}
+	data, err := cli.GetObject(ctx, "test", "flags", "t")
+	if err != nil || len(data) != 0 {
+		t.Fatalf("GetObject after O_TRUNC = %q, %v, want empty", data, err)
+	}
+}
+
+func TestOpenAppendWritesAtEnd(t *testing.T) {
+	cli := fakeClient(t, "flags2")
+	ctx := context.Background()
+	f := openFileNode(t, cli, "flags2", "a", "start-")
+
+	fh, _, errno := f.Open(ctx, syscall.O_WRONLY|syscall.O_APPEND)
+	if errno != 0 {
+		t.Fatalf("Open: %v", errno)
+	}
+	// The offset is ignored for append handles.
+	if _, errno := f.Write(ctx, fh, []byte("end"), 0); errno != 0 {
+		t.Fatalf("Write: %v", errno)
+	}
+	if errno := f.Flush(ctx, fh); errno != 0 {
+		t.Fatalf("Flush: %v", errno)
+	}
+	data, err := cli.GetObject(ctx, "test", "flags2", "a")
+	if err != nil || string(data) != "start-end" {
+		t.Fatalf("GetObject after append = %q, %v, want start-end", data, err)
+	}
+}
+
+func TestOpenWriteOnlySkipsDownload(t *testing.T) {
+	cli := fakeClient(t, "flags3")
+	ctx := context.Background()
+	f := openFileNode(t, cli, "flags3", "w", "existing")
+
+	fh, _, errno := f.Open(ctx, syscall.O_WRONLY)
+	if errno != 0 {
+		t.Fatalf("Open: %v", errno)
+	}
+	h := fh.(*s3FileHandle)
+	if len(h.data) != 0 {
+		t.Fatalf("O_WRONLY open buffered %d bytes of old content, want none", len(h.data))
+	}
+	if _, errno := f.Write(ctx, fh, []byte("new"), 0); errno != 0 {
+		t.Fatalf("Write: %v", errno)
+	}
+	if errno := f.Flush(ctx, fh); errno != 0 {
+		t.Fatalf("Flush: %v", errno)
+	}
+	data, err := cli.GetObject(ctx, "test", "flags3", "w")
+	if err != nil || string(data) != "new" {
+		t.Fatalf("GetObject after rewrite = %q, %v, want new", data, err)
+	}
+}
+
+// TestRenameFile moves a single object between directory nodes.
+func TestRenameFile(t *testing.T) {
+	cli := fakeClient(t, "mvf")
+	ctx := context.Background()
+	if err := cli.PutObject(ctx, "test", "mvf", "a/x", []byte("x")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	src := &s3Root{cli: cli, bucket: "mvf", prefix: "a/"}
+	dst := &s3Root{cli: cli, bucket: "mvf", prefix: "b/"}
+	if errno := src.Rename(ctx, "x", dst, "y", 0); errno != 0 {
+		t.Fatalf("Rename: %v", errno)
+	}
+	if ok, _ := cli.ObjectExists(ctx, "test", "mvf", "a/x"); ok {
+		t.Fatal("source key still exists after rename")
+	}
+	data, err := cli.GetObject(ctx, "test", "mvf", "b/y")
+	if err != nil || string(data) != "x" {
+		t.Fatalf("GetObject(b/y) = %q, %v", data, err)
+	}
+}
+
+func TestGetattrHonorsModeOptions(t *testing.T) {
+	opt := defaultOption
+	opt.FileMode = 0444
+	opt.DirMode = 0555
+	cli, err := NewS3ClientWithAPI(opt, s3test.New())
+	if err != nil {
+		t.Fatalf("NewS3ClientWithAPI: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := cli.CreateBucket(ctx, "test", "modes"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	f := openFileNode(t, cli, "modes", "plain", "data")
+
+	var out fuse.AttrOut
+	if errno := f.Getattr(ctx, nil, &out); errno != 0 {
+		t.Fatalf("Getattr: %v", errno)
+	}
+	if out.Mode != fuse.S_IFREG|0444 {
+		t.Fatalf("file mode = %o, want %o", out.Mode, fuse.S_IFREG|0444)
+	}
+
+	root := &s3Root{cli: cli, bucket: "modes"}
+	if errno := root.Getattr(ctx, nil, &out); errno != 0 {
+		t.Fatalf("Getattr dir: %v", errno)
+	}
+	if out.Mode != fuse.S_IFDIR|0555 {
+		t.Fatalf("dir mode = %o, want %o", out.Mode, fuse.S_IFDIR|0555)
+	}
+
+	// A mode persisted in object metadata still wins over the default.
+	withMode := openFileNode(t, cli, "modes", "chmodded", "data")
+	withMode.mode = 0600
+	withMode.attrLoaded = true
+	if errno := withMode.Getattr(ctx, nil, &out); errno != 0 {
+		t.Fatalf("Getattr: %v", errno)
+	}
+	if out.Mode != fuse.S_IFREG|0600 {
+		t.Fatalf("file mode = %o, want metadata's %o", out.Mode, fuse.S_IFREG|0600)
+	}
+}
+
+func TestGetattrHonorsOwnerOverride(t *testing.T) {
+	opt := defaultOption
+	opt.UID = 1234
+	opt.GID = 5678
+	cli, err := NewS3ClientWithAPI(opt, s3test.New())
+	if err != nil {
+		t.Fatalf("NewS3ClientWithAPI: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := cli.CreateBucket(ctx, "test", "owner"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+
+	f := openFileNode(t, cli, "owner", "f", "data")
+	// Metadata ownership loses to an explicit override.
+	f.uid, f.gid = 42, 42
+	f.attrLoaded = true
+	var out fuse.AttrOut
+	if errno := f.Getattr(ctx, nil, &out); errno != 0 {
+		t.Fatalf("Getattr: %v", errno)
+	}
+	if out.Uid != 1234 || out.Gid != 5678 {
+		t.Fatalf("owner = %d:%d, want 1234:5678", out.Uid, out.Gid)
+	}
+
+	root := &s3Root{cli: cli, bucket: "owner"}
+	if errno := root.Getattr(ctx, nil, &out); errno != 0 {
+		t.Fatalf("Getattr dir: %v", errno)
+	}
+	if out.Uid != 1234 || out.Gid != 5678 {
+		t.Fatalf("dir owner = %d:%d, want 1234:5678", out.Uid, out.Gid)
+	}
+
+	// Without an override the mounting user is reported.
+	plain := fakeClient(t, "owner2")
+	g := openFileNode(t, plain, "owner2", "g", "data")
+	g.attrLoaded = true
+	if errno := g.Getattr(ctx, nil, &out); errno != 0 {
+		t.Fatalf("Getattr: %v", errno)
+	}
+	if out.Uid != uint32(os.Getuid()) || out.Gid != uint32(os.Getgid()) {
+		t.Fatalf("owner = %d:%d, want the mounting user %d:%d", out.Uid, out.Gid, os.Getuid(), os.Getgid())
+	}
+}
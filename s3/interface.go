@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Client is the storage interface the mount and surrounding tooling
+// program against. *s3Client is the canonical implementation; the
+// indirection lets alternate backends — or the s3test fake wired through
+// NewS3ClientWithAPI — stand in without touching callers.
+type Client interface {
+	// Object operations. userID identifies the caller for policy
+	// bookkeeping and is not sent to the backend.
+	PutObject(ctx context.Context, userID, bucket, path string, data []byte, opts ...PutOption) error
+	UploadObject(ctx context.Context, userID, bucket, path string, reader io.Reader, opts ...PutOption) error
+	HeadObject(ctx context.Context, userID, bucket, path string) (*Object, error)
+	ObjectExists(ctx context.Context, userID, bucket, path string) (bool, error)
+	GetObject(ctx context.Context, userID, bucket, path string) ([]byte, error)
+	GetObjectReader(ctx context.Context, userID, bucket, path string) (*S3Node, error)
+	DownloadTo(ctx context.Context, userID, bucket, path string, w io.WriterAt) (int64, error)
+	ListObject(ctx context.Context, userID, bucket, prefix string) ([]Object, error)
+	ListObjectRecursive(ctx context.Context, userID, bucket, prefix string) ([]Object, error)
+	ListObjectRecursiveFunc(ctx context.Context, userID, bucket, prefix string, fn func(Object) error) error
+	UpdateObjectMetadata(ctx context.Context, userID, bucket, path string, contentType string, metadata map[string]string) error
+	CopyObject(ctx context.Context, userID, bucket, src, dst string) error
+	DeleteObject(ctx context.Context, userID, bucket, path string) error
+	Purge(ctx context.Context, userID, bucket, prefix string) error
+	PresignObject(ctx context.Context, userID, bucket, path string) (string, error)
+
+	// Bucket operations.
+	ListBuckets(ctx context.Context, userID string) ([]Bucket, error)
+	CreateBucket(ctx context.Context, userID, name string, tags ...map[string]string) (*Bucket, error)
+	DeleteBucket(ctx context.Context, userID, name string) error
+	PutBucketPolicy(ctx context.Context, bucket string, policy *BucketPolicy) error
+	PutBucketTagging(ctx context.Context, bucket string, tags map[string]string) error
+	GetBucketTagging(ctx context.Context, bucket string) (map[string]string, error)
+	AbortIncompleteUploads(ctx context.Context, bucket, prefix string, olderThan time.Duration) (int, error)
+
+	// Close releases the client's resources, draining any write-back
+	// queue first. The client must not be used afterwards.
+	Close() error
+}
+
+var _ Client = (*s3Client)(nil)
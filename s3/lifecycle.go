@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// LifecycleRule is the subset of S3 lifecycle configuration this module
+// manages: expiry, one storage-class transition and multipart-upload
+// cleanup, each scoped to a key prefix. Zero day counts leave the
+// corresponding action out of the rule.
+type LifecycleRule struct {
+	// ID names the rule; empty IDs get one derived from the prefix.
+	ID string
+	// Prefix scopes the rule; empty applies it to the whole bucket.
+	Prefix string
+	// ExpirationDays deletes objects this many days after creation.
+	ExpirationDays int32
+	// TransitionDays moves objects to TransitionStorageClass (e.g.
+	// GLACIER) this many days after creation.
+	TransitionDays         int32
+	TransitionStorageClass string
+	// AbortIncompleteDays aborts multipart uploads still unfinished this
+	// many days after initiation.
+	AbortIncompleteDays int32
+}
+
+// toSDK renders the rule in the SDK's representation.
+func (r LifecycleRule) toSDK() types.LifecycleRule {
+	id := r.ID
+	if id == "" {
+		id = "s3fs-" + r.Prefix
+	}
+	rule := types.LifecycleRule{
+		ID:     aws.String(id),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilterMemberPrefix{Value: r.Prefix},
+	}
+	if r.ExpirationDays > 0 {
+		rule.Expiration = &types.LifecycleExpiration{Days: aws.Int32(r.ExpirationDays)}
+	}
+	if r.TransitionDays > 0 {
+		rule.Transitions = []types.Transition{{
+			Days:         aws.Int32(r.TransitionDays),
+			StorageClass: types.TransitionStorageClass(r.TransitionStorageClass),
+		}}
+	}
+	if r.AbortIncompleteDays > 0 {
+		rule.AbortIncompleteMultipartUpload = &types.AbortIncompleteMultipartUpload{
+			DaysAfterInitiation: aws.Int32(r.AbortIncompleteDays),
+		}
+	}
+	return rule
+}
+
+// lifecycleFromSDK is the inverse of toSDK, dropping anything this
+// module doesn't model.
+func lifecycleFromSDK(rule types.LifecycleRule) LifecycleRule {
+	r := LifecycleRule{ID: aws.ToString(rule.ID)}
+	if f, ok := rule.Filter.(*types.LifecycleRuleFilterMemberPrefix); ok {
+		r.Prefix = f.Value
+	}
+	if rule.Expiration != nil {
+		r.ExpirationDays = aws.ToInt32(rule.Expiration.Days)
+	}
+	if len(rule.Transitions) > 0 {
+		r.TransitionDays = aws.ToInt32(rule.Transitions[0].Days)
+		r.TransitionStorageClass = string(rule.Transitions[0].StorageClass)
+	}
+	if rule.AbortIncompleteMultipartUpload != nil {
+		r.AbortIncompleteDays = aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+	}
+	return r
+}
+
+// PutBucketLifecycle replaces the bucket's lifecycle configuration with
+// rules. An empty slice is rejected by S3; use the console or raw SDK to
+// delete a configuration outright.
+func (c *s3Client) PutBucketLifecycle(ctx context.Context, bucket string, rules []LifecycleRule) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	sdkRules := make([]types.LifecycleRule, len(rules))
+	for i, r := range rules {
+		sdkRules[i] = r.toSDK()
+	}
+	_, err := c.cli.PutBucketLifecycleConfiguration(ctx, &s3v2.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(bucket),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: sdkRules},
+	})
+	if err != nil {
+		c.log.Errorf("put bucket lifecycle %s error: %v", bucket, err)
+		return fmt.Errorf("put bucket lifecycle %s: %w", bucket, classify(err))
+	}
+	return nil
+}
+
+// GetBucketLifecycle returns the bucket's lifecycle rules; a bucket with
+// no configuration returns an empty slice rather than an error.
+func (c *s3Client) GetBucketLifecycle(ctx context.Context, bucket string) ([]LifecycleRule, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetBucketLifecycleConfiguration(ctx, &s3v2.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if apiErrorCode(err) == "NoSuchLifecycleConfiguration" {
+			return nil, nil
+		}
+		c.log.Errorf("get bucket lifecycle %s error: %v", bucket, err)
+		return nil, fmt.Errorf("get bucket lifecycle %s: %w", bucket, classify(err))
+	}
+	rules := make([]LifecycleRule, len(out.Rules))
+	for i, rule := range out.Rules {
+		rules[i] = lifecycleFromSDK(rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,35 @@
+package s3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBucketLifecycleRoundTrip(t *testing.T) {
+	cli := fakeClient(t, "lifecycle")
+	ctx := context.Background()
+
+	// A bucket without a configuration reports no rules, not an error.
+	rules, err := cli.GetBucketLifecycle(ctx, "lifecycle")
+	if err != nil || rules != nil {
+		t.Fatalf("GetBucketLifecycle on fresh bucket = %v, %v; want nil, nil", rules, err)
+	}
+
+	want := []LifecycleRule{
+		{ID: "tmp-expiry", Prefix: "tmp/", ExpirationDays: 7, AbortIncompleteDays: 2},
+		{Prefix: "archive/", TransitionDays: 30, TransitionStorageClass: "GLACIER"},
+	}
+	if err := cli.PutBucketLifecycle(ctx, "lifecycle", want); err != nil {
+		t.Fatalf("PutBucketLifecycle: %v", err)
+	}
+	got, err := cli.GetBucketLifecycle(ctx, "lifecycle")
+	if err != nil {
+		t.Fatalf("GetBucketLifecycle: %v", err)
+	}
+	// The empty ID comes back with the generated name.
+	want[1].ID = "s3fs-archive/"
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rules round-tripped to %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,21 @@
+package s3
+
+import "github.com/ThierryZhou/go-s3fs/fs"
+
+// Logger is the logging surface the client writes to. Embedders can
+// supply their own structured implementation through Option.Logger, or
+// a discard implementation to silence the client entirely; by default
+// messages go through the leveled fs helpers onto logrus.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// fsLogger is the default Logger, forwarding to the fs package helpers
+// so the log_level option keeps working.
+type fsLogger struct{}
+
+func (fsLogger) Debugf(format string, args ...interface{}) { fs.Debugf(format, args...) }
+func (fsLogger) Warnf(format string, args ...interface{})  { fs.Warnf(format, args...) }
+func (fsLogger) Errorf(format string, args ...interface{}) { fs.Errorf(format, args...) }
@@ -0,0 +1,114 @@
+package s3
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// S3 sends user metadata as x-amz-meta-* HTTP headers, so keys must be
+// ASCII and come back lowercased. Arbitrary keys — non-ASCII attribute
+// names set through xattrs, say — would silently change spelling or be
+// rejected by the backend. encodeMetadata therefore rewrites every key
+// to the safe alphabet before upload and records the original spellings
+// in one reserved mapping entry; decodeMetadata restores them on read.
+
+// metaKeyMap is the reserved metadata key holding the sanitized→original
+// mapping, as &-joined pairs with the original query-escaped. It never
+// surfaces to callers.
+const metaKeyMap = "s3fs-keymap"
+
+// sanitizeMetadataKey rewrites k into the alphabet S3 preserves
+// faithfully: lowercase letters, digits and hyphens. Uppercase is
+// folded, anything else becomes a hyphen, and an empty result falls
+// back to "x" so the entry is not lost outright.
+func sanitizeMetadataKey(k string) string {
+	var sb strings.Builder
+	for _, r := range k {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			sb.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			sb.WriteRune(r + ('a' - 'A'))
+		default:
+			sb.WriteByte('-')
+		}
+	}
+	if sb.Len() == 0 {
+		return "x"
+	}
+	return sb.String()
+}
+
+// encodeMetadata maps caller-facing metadata to what goes on the wire:
+// keys are sanitized, prefixed with the configured MetadataPrefix, and
+// any key whose spelling changed is recorded under metaKeyMap so
+// decodeMetadata can undo the rewrite. Colliding sanitized keys get a
+// numeric suffix; iteration is sorted so the suffixes are deterministic.
+func (c *s3Client) encodeMetadata(md map[string]string) map[string]string {
+	if len(md) == 0 {
+		return md
+	}
+	prefix := c.opt.MetadataPrefix
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(map[string]string, len(md)+1)
+	var renames []string
+	for _, k := range keys {
+		base := prefix + sanitizeMetadataKey(k)
+		sk := base
+		for i := 2; ; i++ {
+			if _, taken := out[sk]; !taken {
+				break
+			}
+			sk = base + "-" + strconv.Itoa(i)
+		}
+		out[sk] = md[k]
+		if sk != prefix+k {
+			renames = append(renames, sk+"="+url.QueryEscape(k))
+		}
+	}
+	if len(renames) > 0 {
+		out[prefix+metaKeyMap] = strings.Join(renames, "&")
+	}
+	return out
+}
+
+// decodeMetadata is the inverse of encodeMetadata: it strips the
+// configured prefix, restores original key spellings from the metaKeyMap
+// entry and drops the entry itself. Metadata written by other tools
+// passes through with only the prefix strip.
+func (c *s3Client) decodeMetadata(md map[string]string) map[string]string {
+	if len(md) == 0 {
+		return md
+	}
+	prefix := c.opt.MetadataPrefix
+	orig := map[string]string{}
+	if m, ok := md[prefix+metaKeyMap]; ok {
+		for _, pair := range strings.Split(m, "&") {
+			sk, esc, ok := strings.Cut(pair, "=")
+			if !ok {
+				continue
+			}
+			if k, err := url.QueryUnescape(esc); err == nil {
+				orig[sk] = k
+			}
+		}
+	}
+	out := make(map[string]string, len(md))
+	for k, v := range md {
+		if k == prefix+metaKeyMap {
+			continue
+		}
+		name := strings.TrimPrefix(k, prefix)
+		if o, ok := orig[k]; ok {
+			name = o
+		}
+		out[name] = v
+	}
+	return out
+}
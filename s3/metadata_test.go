@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ThierryZhou/go-s3fs/s3/s3test"
+)
+
+func TestSanitizeMetadataKey(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"mtime", "mtime"},
+		{"Content-Class", "content-class"},
+		{"user_name", "user-name"},
+		{"数据", "--"},
+		{"", "x"},
+	}
+	for _, tc := range cases {
+		if got := sanitizeMetadataKey(tc.in); got != tc.want {
+			t.Errorf("sanitizeMetadataKey(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestMetadataRoundTripsOriginalKeys(t *testing.T) {
+	cli := fakeClient(t, "meta-keys")
+	ctx := context.Background()
+
+	md := map[string]string{
+		"mtime":    "1700000000",
+		"Märchen":  "grimm",
+		"数据集":      "train",
+		"user_тип": "a",
+	}
+	if err := cli.PutObject(ctx, "test", "meta-keys", "obj", []byte("x"), WithMetadata(md)); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	head, err := cli.HeadObject(ctx, "test", "meta-keys", "obj")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if !reflect.DeepEqual(head.Metadata, md) {
+		t.Fatalf("Metadata = %v, want original keys %v", head.Metadata, md)
+	}
+}
+
+func TestMetadataPrefixIsTransparent(t *testing.T) {
+	opt := defaultOption
+	opt.MetadataPrefix = "s3fs-"
+	cli, err := NewS3ClientWithAPI(opt, s3test.New())
+	if err != nil {
+		t.Fatalf("NewS3ClientWithAPI: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := cli.CreateBucket(ctx, "test", "meta-prefix"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if err := cli.PutObject(ctx, "test", "meta-prefix", "obj", []byte("x"), WithMetadata(map[string]string{"mode": "644"})); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	head, err := cli.HeadObject(ctx, "test", "meta-prefix", "obj")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if head.Metadata["mode"] != "644" {
+		t.Fatalf("Metadata = %v, want mode=644 with the prefix stripped", head.Metadata)
+	}
+	// On the wire the key carries the prefix.
+	wire := cli.encodeMetadata(map[string]string{"mode": "644"})
+	if wire["s3fs-mode"] != "644" {
+		t.Fatalf("encoded = %v, want s3fs-mode on the wire", wire)
+	}
+}
+
+func TestEncodeMetadataCollisions(t *testing.T) {
+	cli := fakeClient(t, "meta-collide")
+	wire := cli.encodeMetadata(map[string]string{"a_b": "1", "a-b": "2", "A-B": "3"})
+	got := cli.decodeMetadata(wire)
+	want := map[string]string{"a_b": "1", "a-b": "2", "A-B": "3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip = %v, want %v (wire %v)", got, want, wire)
+	}
+}
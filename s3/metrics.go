@@ -0,0 +1,131 @@
+package s3
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsSnapshot is a point-in-time copy of the package's operation
+// counters. Counters are process-wide, not per client: a mount is one
+// process, and operators scraping it care about the aggregate.
+type MetricsSnapshot struct {
+	// Requests counts backend calls by S3 operation name, failures
+	// included.
+	Requests map[string]uint64
+	// Errors counts backend calls that returned an error.
+	Errors uint64
+	// BytesUploaded and BytesDownloaded count object payload bytes, not
+	// wire bytes — multipart overhead and listings are excluded.
+	BytesUploaded   uint64
+	BytesDownloaded uint64
+	// CacheHits and CacheMisses aggregate the read caches of every
+	// client in the process; CacheStats remains the per-client view.
+	CacheHits   uint64
+	CacheMisses uint64
+}
+
+// counters holds the live values behind Metrics. The per-operation map
+// takes a mutex; everything on the hot byte-counting paths is atomic.
+type metricsCounters struct {
+	mu       sync.Mutex
+	requests map[string]uint64
+
+	errors      atomic.Uint64
+	bytesUp     atomic.Uint64
+	bytesDown   atomic.Uint64
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+}
+
+var counters = &metricsCounters{requests: make(map[string]uint64)}
+
+// record notes one backend call of the named operation and whether it
+// failed.
+func (m *metricsCounters) record(op string, err error) {
+	m.mu.Lock()
+	m.requests[op]++
+	m.mu.Unlock()
+	if err != nil {
+		m.errors.Add(1)
+	}
+}
+
+// meteredReader counts upload payload bytes as the transfer manager
+// consumes them, since a streaming body's length is unknown up front.
+type meteredReader struct {
+	r io.Reader
+}
+
+func (mr meteredReader) Read(p []byte) (int, error) {
+	n, err := mr.r.Read(p)
+	if n > 0 {
+		counters.bytesUp.Add(uint64(n))
+	}
+	return n, err
+}
+
+// Metrics returns a snapshot of the process-wide operation counters.
+func Metrics() MetricsSnapshot {
+	snap := MetricsSnapshot{
+		Errors:          counters.errors.Load(),
+		BytesUploaded:   counters.bytesUp.Load(),
+		BytesDownloaded: counters.bytesDown.Load(),
+		CacheHits:       counters.cacheHits.Load(),
+		CacheMisses:     counters.cacheMisses.Load(),
+	}
+	counters.mu.Lock()
+	snap.Requests = make(map[string]uint64, len(counters.requests))
+	for op, n := range counters.requests {
+		snap.Requests[op] = n
+	}
+	counters.mu.Unlock()
+	return snap
+}
+
+// metricsCollector exposes the snapshot to Prometheus on every scrape,
+// so no background goroutine or update hook is needed.
+type metricsCollector struct {
+	requests *prometheus.Desc
+	errors   *prometheus.Desc
+	bytes    *prometheus.Desc
+	cacheOps *prometheus.Desc
+}
+
+func (mc *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mc.requests
+	ch <- mc.errors
+	ch <- mc.bytes
+	ch <- mc.cacheOps
+}
+
+func (mc *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	snap := Metrics()
+	for op, n := range snap.Requests {
+		ch <- prometheus.MustNewConstMetric(mc.requests, prometheus.CounterValue, float64(n), op)
+	}
+	ch <- prometheus.MustNewConstMetric(mc.errors, prometheus.CounterValue, float64(snap.Errors))
+	ch <- prometheus.MustNewConstMetric(mc.bytes, prometheus.CounterValue, float64(snap.BytesUploaded), "upload")
+	ch <- prometheus.MustNewConstMetric(mc.bytes, prometheus.CounterValue, float64(snap.BytesDownloaded), "download")
+	ch <- prometheus.MustNewConstMetric(mc.cacheOps, prometheus.CounterValue, float64(snap.CacheHits), "hit")
+	ch <- prometheus.MustNewConstMetric(mc.cacheOps, prometheus.CounterValue, float64(snap.CacheMisses), "miss")
+}
+
+// RegisterPrometheus registers the package's counters with reg, for
+// deployments that run the mount as a service and scrape it. Callers
+// that don't use Prometheus never pay for it; the counters themselves
+// are always maintained.
+func RegisterPrometheus(reg prometheus.Registerer) error {
+	return reg.Register(&metricsCollector{
+		requests: prometheus.NewDesc("s3fs_requests_total",
+			"Backend S3 calls by operation, failures included.", []string{"operation"}, nil),
+		errors: prometheus.NewDesc("s3fs_request_errors_total",
+			"Backend S3 calls that returned an error.", nil, nil),
+		bytes: prometheus.NewDesc("s3fs_object_bytes_total",
+			"Object payload bytes by transfer direction.", []string{"direction"}, nil),
+		cacheOps: prometheus.NewDesc("s3fs_cache_lookups_total",
+			"Read cache lookups by result.", []string{"result"}, nil),
+	})
+}
@@ -0,0 +1,70 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestMetricsCountOperations checks that the package counters move with
+// backend traffic. Counters are process-wide, so the assertions compare
+// deltas rather than absolute values to stay independent of test order.
+func TestMetricsCountOperations(t *testing.T) {
+	cli := fakeClient(t, "metrics")
+	ctx := context.Background()
+	before := Metrics()
+
+	if err := cli.PutObject(ctx, "test", "metrics", "k", []byte("payload")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	if _, err := cli.GetObject(ctx, "test", "metrics", "k"); err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	if _, err := cli.GetObject(ctx, "test", "metrics", "missing"); err == nil {
+		t.Fatal("GetObject of a missing key succeeded")
+	}
+
+	after := Metrics()
+	if got := after.Requests["PutObject"] - before.Requests["PutObject"]; got != 1 {
+		t.Fatalf("PutObject count grew by %d, want 1", got)
+	}
+	if after.Requests["GetObject"] <= before.Requests["GetObject"] {
+		t.Fatal("GetObject count did not grow")
+	}
+	if got := after.BytesUploaded - before.BytesUploaded; got != uint64(len("payload")) {
+		t.Fatalf("uploaded bytes grew by %d, want %d", got, len("payload"))
+	}
+	if got := after.BytesDownloaded - before.BytesDownloaded; got != uint64(len("payload")) {
+		t.Fatalf("downloaded bytes grew by %d, want %d", got, len("payload"))
+	}
+	if after.Errors <= before.Errors {
+		t.Fatal("error count did not grow after a failed read")
+	}
+}
+
+func TestRegisterPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if err := RegisterPrometheus(reg); err != nil {
+		t.Fatalf("RegisterPrometheus: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	want := map[string]bool{
+		"s3fs_request_errors_total": false,
+		"s3fs_object_bytes_total":   false,
+		"s3fs_cache_lookups_total":  false,
+	}
+	for _, f := range families {
+		if _, ok := want[f.GetName()]; ok {
+			want[f.GetName()] = true
+		}
+	}
+	for name, seen := range want {
+		if !seen {
+			t.Fatalf("metric %s missing from scrape", name)
+		}
+	}
+}
@@ -0,0 +1,622 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThierryZhou/go-s3fs/fs"
+)
+
+// Option carries the connection settings for an S3-compatible endpoint.
+type Option struct {
+	// URL is the endpoint of the object store, e.g. https://minio.local:9000.
+	// Validate requires it for credentialed mounts; only anonymous ones,
+	// which fall back to the default AWS endpoint for Region, may leave
+	// it empty.
+	URL string
+	// Region is the signing region. Self-hosted gateways usually accept any
+	// value here.
+	Region string
+	// AccessKey and SecretKey are the static credentials used to sign
+	// requests. When set they take precedence over every other source.
+	AccessKey string
+	SecretKey string
+	// CredentialsSource picks where credentials come from when no inline
+	// keys are given: "env" for the AWS_ACCESS_KEY_ID family of
+	// environment variables, "shared" for ~/.aws/credentials, or empty
+	// for the SDK's default chain (which tries both).
+	CredentialsSource string
+	// Profile names the shared-config profile to use with the "shared"
+	// source; empty means the default profile.
+	Profile string
+	// Anonymous signs nothing: requests go out without credentials, which
+	// is all a world-readable bucket needs. It beats every credential
+	// source except inline keys, and writes will fail with AccessDenied
+	// on any sane backend.
+	Anonymous bool
+	// UserID identifies the calling user for policy/share bookkeeping. It is
+	// not sent to the backend.
+	UserID string
+	// AttrTimeout is how long the mount caches directory listings and
+	// attributes before re-listing from S3.
+	AttrTimeout time.Duration
+	// CacheDir enables the local read-through cache of object bodies when
+	// set. CacheMaxSize bounds its on-disk footprint; least recently used
+	// entries are evicted beyond it.
+	CacheDir     string
+	CacheMaxSize fs.SizeSuffix
+	// WriteBack makes closes on the mount return immediately while the
+	// content is uploaded in the background. Use Sync to wait for pending
+	// uploads; a crash before they finish loses the staged data.
+	WriteBack bool
+	// CacheMode selects the caching strategy in one word, in the spirit
+	// of rclone's --vfs-cache-mode, overriding the individual CacheDir
+	// and WriteBack toggles when set:
+	//
+	//	off    - stream everything; every read and write hits the backend
+	//	         synchronously. Slowest, but nothing is ever stale or lost.
+	//	reads  - read-through disk cache (needs CacheDir); writes stay
+	//	         synchronous, so a successful close means the data is on
+	//	         the backend.
+	//	writes - write-back queue; closes return before the upload
+	//	         finishes, and a crash loses whatever was still queued.
+	//	         Reads stream.
+	//	full   - both of the above: fastest, weakest guarantees.
+	//
+	// Empty keeps the legacy behavior where CacheDir and WriteBack are
+	// honored directly.
+	CacheMode string
+	// PartSize and UploadConcurrency tune multipart uploads. PartSize must
+	// be at least 5MiB per S3 rules; zero values keep the transfer
+	// manager's defaults.
+	PartSize          fs.SizeSuffix
+	UploadConcurrency int
+	// DownloadPartSize and DownloadConcurrency tune ranged downloads the
+	// same way; zero values keep the transfer manager's defaults. Bigger
+	// parts with more concurrency trade memory for throughput on
+	// high-latency links.
+	DownloadPartSize    fs.SizeSuffix
+	DownloadConcurrency int
+	// BwLimit caps streaming read bandwidth in bytes per second. Zero
+	// means unlimited.
+	BwLimit fs.SizeSuffix
+	// BufferSize is the per-transfer read-ahead; zero keeps the default.
+	// It is split into asyncreader-sized buffers, so values below one
+	// buffer disable read-ahead.
+	BufferSize fs.SizeSuffix
+	// ACL is the canned ACL applied to every uploaded object and created
+	// bucket, e.g. "private" or "public-read". Empty leaves the backend's
+	// default (the bucket owner's private). Unknown values are rejected
+	// when the client is built, not silently sent.
+	ACL string
+	// MetadataPrefix is prepended to every user-metadata key this mount
+	// writes (after the x-amz-meta- the protocol already adds) and
+	// stripped on read, so several tools can share a bucket without
+	// trampling each other's metadata. Empty writes bare keys.
+	MetadataPrefix string
+	// AllowEmptyFiles permits zero-byte uploads. Plain S3 accepts them
+	// and the default keeps them on; turn it off for compatible backends
+	// that reject an empty body, making such writes fail with
+	// ErrorCantUploadEmptyFiles instead of a backend error.
+	AllowEmptyFiles bool
+	// Concurrency bounds the parallel S3 calls issued by bulk operations
+	// (DeleteBucket, Purge, recursive rename) and the write-back workers.
+	// Zero keeps the default of 32; lower it against rate-limited
+	// backends. Negative values are rejected.
+	Concurrency int
+	// OperationTimeout bounds each S3 operation, retries included, so a
+	// hung backend can't block the mount forever. The default is an
+	// interactive 30s: without it the retryer's backoff budget can sit
+	// on a FUSE request for many minutes and the mount appears frozen,
+	// where a prompt ETIMEDOUT lets the caller decide. Raise it on slow
+	// links where one object transfer legitimately takes longer, or set
+	// op_timeout=0s to remove the bound — the parser stores that as a
+	// negative duration, since a zero field here means "unset" and is
+	// given the default; code building an Option literally should use
+	// any negative value for the same effect. request_deadline is
+	// accepted as an alias in connection strings.
+	OperationTimeout time.Duration
+	// FreeSpace is what statfs on the mount reports as free. Zero reports
+	// an effectively unlimited 1PiB; set it when quota-aware tools should
+	// see a real budget.
+	FreeSpace fs.SizeSuffix
+	// ReadOnly makes every mutating operation on the mount fail with
+	// EROFS and adds the ro mount option, for safely sharing datasets.
+	ReadOnly bool
+	// FileMode and DirMode are the permission bits presented for objects
+	// and prefixes that carry no mode in their metadata. Zero values mean
+	// the usual 0644 and 0755; set FileMode to 0444 to make a read-only
+	// mount look the part. Connection strings spell them in octal.
+	FileMode uint32
+	DirMode  uint32
+	// UID and GID override the owner reported for every node, so a
+	// dataset can appear owned by a fixed service account no matter who
+	// mounted it. -1 (the default) reports the mounting user; uid/gid
+	// persisted in object metadata beats that default but not an
+	// explicit override.
+	UID int
+	GID int
+	// HTTPProxy routes every backend request through the given proxy URL,
+	// for deployments whose only egress is a corporate proxy. Empty uses
+	// the environment's proxy settings, as Go's default transport does.
+	HTTPProxy string
+	// MaxIdleConns caps the pooled idle connections to the endpoint and
+	// IdleConnTimeout is how long an idle one is kept before closing.
+	// Zero keeps Go's defaults; tune them for high-concurrency mounts or
+	// middleboxes that drop long-idle connections.
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+	// LogLevel tunes per-operation logging: off, error, warn, info or
+	// debug. Empty keeps the process-wide default.
+	LogLevel string
+	// Logger replaces the default logrus-backed logger. It has no
+	// connection-string spelling; embedders set it in code.
+	Logger Logger
+}
+
+var defaultOption = Option{
+	Region:           "us-east-1",
+	AttrTimeout:      10 * time.Second,
+	OperationTimeout: 30 * time.Second,
+	CacheMaxSize:     1 * fs.Gibi,
+	AllowEmptyFiles:  true,
+	UID:              -1,
+	GID:              -1,
+}
+
+// concurrency returns the validated bulk-operation parallelism: the
+// configured value when at least 1, the default otherwise.
+func (o *Option) concurrency() int {
+	if o.Concurrency >= 1 {
+		return o.Concurrency
+	}
+	return MAX_GOROUTES
+}
+
+// ownerUID resolves the uid a node reports: the override when set, the
+// object's persisted uid when it has one, the mounting user otherwise.
+// Directory nodes pass 0 for meta since prefixes persist nothing.
+func (o *Option) ownerUID(meta uint32) uint32 {
+	if o.UID >= 0 {
+		return uint32(o.UID)
+	}
+	if meta != 0 {
+		return meta
+	}
+	return uint32(os.Getuid())
+}
+
+// ownerGID is ownerUID for the group.
+func (o *Option) ownerGID(meta uint32) uint32 {
+	if o.GID >= 0 {
+		return uint32(o.GID)
+	}
+	if meta != 0 {
+		return meta
+	}
+	return uint32(os.Getgid())
+}
+
+// fileMode and dirMode return the configured default permission bits,
+// falling back to the conventional 0644/0755 so a zero Option still
+// presents a usable tree.
+func (o *Option) fileMode() uint32 {
+	if o.FileMode != 0 {
+		return o.FileMode & 07777
+	}
+	return 0644
+}
+
+func (o *Option) dirMode() uint32 {
+	if o.DirMode != 0 {
+		return o.DirMode & 07777
+	}
+	return 0755
+}
+
+// cacheMode resolves CacheMode (or, when empty, the legacy CacheDir and
+// WriteBack toggles) into the two concrete switches the client wires up.
+func (o *Option) cacheMode() (readCache, writeBack bool, err error) {
+	switch o.CacheMode {
+	case "":
+		return o.CacheDir != "", o.WriteBack, nil
+	case "off":
+		return false, false, nil
+	case "reads":
+		readCache = true
+	case "writes":
+		writeBack = true
+	case "full":
+		readCache, writeBack = true, true
+	default:
+		return false, false, fmt.Errorf("unknown cache mode %q (want off, reads, writes or full)", o.CacheMode)
+	}
+	if readCache && o.CacheDir == "" {
+		return false, false, fmt.Errorf("cache mode %q needs cache_dir", o.CacheMode)
+	}
+	return readCache, writeBack, nil
+}
+
+// WithDefaults returns a copy of o with defaultOption filled into every
+// empty field that has a default. Zero means "unset" here, the same
+// reading the connection-string parser uses; fields whose zero value is
+// meaningful in its own right (AllowEmptyFiles, UID, GID) are left
+// untouched — start from defaultOption when building an Option literal
+// if those defaults are wanted. A negative OperationTimeout survives as
+// "no deadline".
+func (o *Option) WithDefaults() *Option {
+	out := *o
+	if out.Region == "" {
+		out.Region = defaultOption.Region
+	}
+	if out.AttrTimeout == 0 {
+		out.AttrTimeout = defaultOption.AttrTimeout
+	}
+	if out.OperationTimeout == 0 {
+		out.OperationTimeout = defaultOption.OperationTimeout
+	}
+	if out.CacheMaxSize == 0 {
+		out.CacheMaxSize = defaultOption.CacheMaxSize
+	}
+	return &out
+}
+
+// Validate reports the first clearly-wrong field, so a mistyped
+// connection string fails at construction with a message naming the
+// option instead of obscurely on the first request. It leaves judgement
+// calls (unreachable endpoints, wrong credentials) to the backend.
+func (o *Option) Validate() error {
+	if o.URL == "" && !o.Anonymous {
+		return fmt.Errorf("option url is required (only anonymous mounts may omit it)")
+	}
+	for _, ep := range splitEndpoints(o.URL) {
+		u, err := url.Parse(ep)
+		if err != nil {
+			return fmt.Errorf("invalid url %q: %w", ep, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid url %q: scheme must be http or https", ep)
+		}
+	}
+	if o.Concurrency < 0 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", o.Concurrency)
+	}
+	if o.UploadConcurrency < 0 {
+		return fmt.Errorf("upload_concurrency must be at least 1, got %d", o.UploadConcurrency)
+	}
+	if o.DownloadConcurrency < 0 {
+		return fmt.Errorf("download_concurrency must be at least 1, got %d", o.DownloadConcurrency)
+	}
+	if o.HTTPProxy != "" {
+		if _, err := url.Parse(o.HTTPProxy); err != nil {
+			return fmt.Errorf("invalid http_proxy %q: %w", o.HTTPProxy, err)
+		}
+	}
+	if err := validACL(o.ACL); err != nil {
+		return err
+	}
+	if _, _, err := o.cacheMode(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// transportTweaks returns the transport adjustments the options ask
+// for, or nil when the default transport is fine. The SDK's buildable
+// HTTP client applies them to its own transport, so its TLS and timeout
+// defaults are kept rather than rebuilt from scratch.
+func (o *Option) transportTweaks() func(*http.Transport) {
+	if o.HTTPProxy == "" && o.MaxIdleConns == 0 && o.IdleConnTimeout == 0 {
+		return nil
+	}
+	return func(tr *http.Transport) {
+		if o.HTTPProxy != "" {
+			if u, err := url.Parse(o.HTTPProxy); err == nil {
+				tr.Proxy = http.ProxyURL(u)
+			}
+		}
+		if o.MaxIdleConns > 0 {
+			tr.MaxIdleConns = o.MaxIdleConns
+			// Every connection goes to the one endpoint, so the global
+			// cap is meaningless unless the per-host cap follows it.
+			tr.MaxIdleConnsPerHost = o.MaxIdleConns
+		}
+		if o.IdleConnTimeout > 0 {
+			tr.IdleConnTimeout = o.IdleConnTimeout
+		}
+	}
+}
+
+// configInfo derives the transfer configuration from the options.
+func (o *Option) configInfo() *fs.ConfigInfo {
+	ci := fs.DefaultConfig()
+	ci.BwLimit = o.BwLimit
+	if o.BufferSize != 0 {
+		ci.BufferSize = o.BufferSize
+	}
+	return ci
+}
+
+// ParseOption parses a comma separated key=value connection string, e.g.
+//
+//	url=https://minio.local:9000,accesskey=AK,secretkey=SK,region=us-east-1
+//
+// Unknown keys are ignored so callers can share one string between layers.
+func ParseOption(args string) Option {
+	opt := defaultOption
+	for _, kv := range strings.Split(args, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		opt.set(k, v)
+	}
+	return opt
+}
+
+// ParseOptionURL parses a DSN-style URL with embedded credentials, e.g.
+//
+//	https://AK:SK@minio.local:9000?region=us-east-1&write_back=true
+//
+// The userinfo becomes the access and secret key, the scheme and host the
+// endpoint, and query parameters accept the same keys as ParseOption.
+// Unlike ParseOption it reports malformed input, since a typo in a single
+// URL would otherwise silently mount with defaults.
+func ParseOptionURL(raw string) (Option, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Option{}, fmt.Errorf("parse option url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Option{}, fmt.Errorf("parse option url: unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return Option{}, fmt.Errorf("parse option url: missing host")
+	}
+	opt := defaultOption
+	opt.URL = u.Scheme + "://" + u.Host
+	if u.User != nil {
+		opt.AccessKey = u.User.Username()
+		if pw, ok := u.User.Password(); ok {
+			opt.SecretKey = pw
+		}
+	}
+	for k, vs := range u.Query() {
+		opt.set(k, vs[len(vs)-1])
+	}
+	return opt, nil
+}
+
+// set applies one connection-string key to the option, ignoring unknown
+// keys and unparsable values.
+func (opt *Option) set(k, v string) {
+	switch strings.ToLower(k) {
+	case "url", "endpoint":
+		opt.URL = v
+	case "region":
+		opt.Region = v
+	case "accesskey", "access_key":
+		opt.AccessKey = v
+	case "secretkey", "secret_key":
+		opt.SecretKey = v
+	case "credentials_source":
+		opt.CredentialsSource = v
+	case "profile":
+		opt.Profile = v
+	case "anonymous":
+		opt.Anonymous = v == "true" || v == "1"
+	case "user", "userid":
+		opt.UserID = v
+	case "attr_timeout":
+		if d, err := time.ParseDuration(v); err == nil {
+			opt.AttrTimeout = d
+		}
+	case "cache_dir":
+		opt.CacheDir = v
+	case "cache_max_size":
+		// Errors keep the default rather than failing the parse.
+		opt.CacheMaxSize.Set(v)
+	case "write_back":
+		opt.WriteBack = v == "true" || v == "1"
+	case "cache_mode":
+		opt.CacheMode = v
+	case "part_size":
+		opt.PartSize.Set(v)
+	case "upload_concurrency":
+		if n, err := strconv.Atoi(v); err == nil {
+			opt.UploadConcurrency = n
+		}
+	case "download_part_size":
+		opt.DownloadPartSize.Set(v)
+	case "download_concurrency":
+		if n, err := strconv.Atoi(v); err == nil {
+			opt.DownloadConcurrency = n
+		}
+	case "bwlimit":
+		opt.BwLimit.Set(v)
+	case "buffer_size":
+		opt.BufferSize.Set(v)
+	case "acl":
+		opt.ACL = v
+	case "metadata_prefix":
+		opt.MetadataPrefix = v
+	case "allow_empty_files":
+		opt.AllowEmptyFiles = v == "true" || v == "1"
+	case "concurrency":
+		if n, err := strconv.Atoi(v); err == nil {
+			opt.Concurrency = n
+		}
+	case "op_timeout", "request_deadline":
+		if d, err := time.ParseDuration(v); err == nil {
+			if d <= 0 {
+				// An explicit 0s means "no bound". Store it negative so
+				// WithDefaults can tell it apart from an unset field,
+				// which gets the 30s default.
+				d = -1
+			}
+			opt.OperationTimeout = d
+		}
+	case "http_proxy":
+		opt.HTTPProxy = v
+	case "max_idle_conns":
+		if n, err := strconv.Atoi(v); err == nil {
+			opt.MaxIdleConns = n
+		}
+	case "idle_conn_timeout":
+		if d, err := time.ParseDuration(v); err == nil {
+			opt.IdleConnTimeout = d
+		}
+	case "free_space":
+		opt.FreeSpace.Set(v)
+	case "read_only", "ro":
+		opt.ReadOnly = v == "true" || v == "1"
+	case "file_mode":
+		if m, err := strconv.ParseUint(v, 8, 32); err == nil {
+			opt.FileMode = uint32(m)
+		}
+	case "dir_mode":
+		if m, err := strconv.ParseUint(v, 8, 32); err == nil {
+			opt.DirMode = uint32(m)
+		}
+	case "uid":
+		if n, err := strconv.Atoi(v); err == nil {
+			opt.UID = n
+		}
+	case "gid":
+		if n, err := strconv.Atoi(v); err == nil {
+			opt.GID = n
+		}
+	case "log_level":
+		opt.LogLevel = v
+	}
+}
+
+// ConnectionString renders o as a string that ParseOption parses back
+// into an equivalent Option, so a configured remote can be persisted or
+// handed to another process losslessly. Only fields that differ from
+// the defaults are emitted. The secret key is included when set — the
+// result is a credential and must be handled like one. Logger has no
+// connection-string spelling and is not represented; values containing
+// "," or "=" cannot be represented either.
+func (o *Option) ConnectionString() string {
+	var parts []string
+	add := func(k, v string) { parts = append(parts, k+"="+v) }
+	d := &defaultOption
+	if o.URL != "" {
+		add("url", o.URL)
+	}
+	if o.Region != d.Region {
+		add("region", o.Region)
+	}
+	if o.AccessKey != "" {
+		add("accesskey", o.AccessKey)
+	}
+	if o.SecretKey != "" {
+		add("secretkey", o.SecretKey)
+	}
+	if o.CredentialsSource != "" {
+		add("credentials_source", o.CredentialsSource)
+	}
+	if o.Profile != "" {
+		add("profile", o.Profile)
+	}
+	if o.Anonymous {
+		add("anonymous", "true")
+	}
+	if o.UserID != "" {
+		add("user", o.UserID)
+	}
+	if o.AttrTimeout != d.AttrTimeout {
+		add("attr_timeout", o.AttrTimeout.String())
+	}
+	if o.CacheDir != "" {
+		add("cache_dir", o.CacheDir)
+	}
+	if o.CacheMaxSize != d.CacheMaxSize {
+		add("cache_max_size", o.CacheMaxSize.String())
+	}
+	if o.WriteBack {
+		add("write_back", "true")
+	}
+	if o.CacheMode != "" {
+		add("cache_mode", o.CacheMode)
+	}
+	if o.PartSize != 0 {
+		add("part_size", o.PartSize.String())
+	}
+	if o.UploadConcurrency != 0 {
+		add("upload_concurrency", strconv.Itoa(o.UploadConcurrency))
+	}
+	if o.DownloadPartSize != 0 {
+		add("download_part_size", o.DownloadPartSize.String())
+	}
+	if o.DownloadConcurrency != 0 {
+		add("download_concurrency", strconv.Itoa(o.DownloadConcurrency))
+	}
+	if o.BwLimit != 0 {
+		add("bwlimit", o.BwLimit.String())
+	}
+	if o.BufferSize != 0 {
+		add("buffer_size", o.BufferSize.String())
+	}
+	if o.ACL != "" {
+		add("acl", o.ACL)
+	}
+	if o.MetadataPrefix != "" {
+		add("metadata_prefix", o.MetadataPrefix)
+	}
+	if !o.AllowEmptyFiles {
+		add("allow_empty_files", "false")
+	}
+	if o.Concurrency != 0 {
+		add("concurrency", strconv.Itoa(o.Concurrency))
+	}
+	switch {
+	case o.OperationTimeout < 0:
+		// The negative no-bound sentinel spells as 0s, matching what
+		// the parser turns back into a negative.
+		add("op_timeout", "0s")
+	case o.OperationTimeout > 0 && o.OperationTimeout != d.OperationTimeout:
+		add("op_timeout", o.OperationTimeout.String())
+	}
+	if o.HTTPProxy != "" {
+		add("http_proxy", o.HTTPProxy)
+	}
+	if o.MaxIdleConns != 0 {
+		add("max_idle_conns", strconv.Itoa(o.MaxIdleConns))
+	}
+	if o.IdleConnTimeout != 0 {
+		add("idle_conn_timeout", o.IdleConnTimeout.String())
+	}
+	if o.FreeSpace != 0 {
+		add("free_space", o.FreeSpace.String())
+	}
+	if o.ReadOnly {
+		add("read_only", "true")
+	}
+	if o.FileMode != 0 {
+		add("file_mode", strconv.FormatUint(uint64(o.FileMode), 8))
+	}
+	if o.DirMode != 0 {
+		add("dir_mode", strconv.FormatUint(uint64(o.DirMode), 8))
+	}
+	if o.UID != d.UID {
+		add("uid", strconv.Itoa(o.UID))
+	}
+	if o.GID != d.GID {
+		add("gid", strconv.Itoa(o.GID))
+	}
+	if o.LogLevel != "" {
+		add("log_level", o.LogLevel)
+	}
+	return strings.Join(parts, ",")
+}
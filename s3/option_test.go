@@ -0,0 +1,224 @@
+package s3
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseOption(t *testing.T) {
+	opt := ParseOption("url=https://minio.local:9000, accesskey=AK,secretkey=SK,attr_timeout=30s,write_back=1,unknown=x")
+	if opt.URL != "https://minio.local:9000" || opt.AccessKey != "AK" || opt.SecretKey != "SK" {
+		t.Fatalf("parsed %+v, want endpoint and keys set", opt)
+	}
+	if opt.AttrTimeout != 30*time.Second || !opt.WriteBack {
+		t.Fatalf("parsed %+v, want attr_timeout=30s write_back=true", opt)
+	}
+	// Untouched fields keep their defaults.
+	if opt.Region != defaultOption.Region || !opt.AllowEmptyFiles {
+		t.Fatalf("parsed %+v, want defaults preserved", opt)
+	}
+}
+
+func TestParseOptionURL(t *testing.T) {
+	opt, err := ParseOptionURL("https://AK:SK@minio.local:9000?region=eu-west-1&write_back=true")
+	if err != nil {
+		t.Fatalf("ParseOptionURL: %v", err)
+	}
+	if opt.URL != "https://minio.local:9000" {
+		t.Errorf("URL = %q, want scheme and host only", opt.URL)
+	}
+	if opt.AccessKey != "AK" || opt.SecretKey != "SK" {
+		t.Errorf("credentials = %q/%q, want AK/SK from userinfo", opt.AccessKey, opt.SecretKey)
+	}
+	if opt.Region != "eu-west-1" || !opt.WriteBack {
+		t.Errorf("query options not applied: %+v", opt)
+	}
+
+	// Userinfo is optional: a bare endpoint still parses.
+	opt, err = ParseOptionURL("http://localhost:9000")
+	if err != nil || opt.URL != "http://localhost:9000" || opt.AccessKey != "" {
+		t.Fatalf("bare endpoint parsed to %+v, %v", opt, err)
+	}
+
+	for _, bad := range []string{"minio.local:9000", "ftp://host", "https://"} {
+		if _, err := ParseOptionURL(bad); err == nil {
+			t.Errorf("ParseOptionURL(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCacheModeResolution(t *testing.T) {
+	cases := []struct {
+		mode      string
+		cacheDir  string
+		writeBack bool
+		wantRead  bool
+		wantWrite bool
+		wantErr   bool
+	}{
+		// Empty mode keeps the legacy toggles.
+		{"", "", false, false, false, false},
+		{"", "/tmp/c", true, true, true, false},
+		{"off", "/tmp/c", true, false, false, false},
+		{"reads", "/tmp/c", false, true, false, false},
+		{"reads", "", false, false, false, true},
+		{"writes", "", false, false, true, false},
+		{"full", "/tmp/c", false, true, true, false},
+		{"everything", "", false, false, false, true},
+	}
+	for _, tc := range cases {
+		opt := defaultOption
+		opt.CacheMode = tc.mode
+		opt.CacheDir = tc.cacheDir
+		opt.WriteBack = tc.writeBack
+		read, write, err := opt.cacheMode()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("cacheMode(%q) error = %v, wantErr %v", tc.mode, err, tc.wantErr)
+			continue
+		}
+		if read != tc.wantRead || write != tc.wantWrite {
+			t.Errorf("cacheMode(%q, dir=%q, wb=%v) = %v, %v; want %v, %v",
+				tc.mode, tc.cacheDir, tc.writeBack, read, write, tc.wantRead, tc.wantWrite)
+		}
+	}
+}
+
+// TestRequestDeadlineDefaults pins the interactive default deadline and
+// its escape hatches: the alias spelling and an explicit 0 to unbound.
+func TestRequestDeadlineDefaults(t *testing.T) {
+	opt := ParseOption("url=https://minio.local:9000")
+	if opt.OperationTimeout != 30*time.Second {
+		t.Fatalf("default OperationTimeout = %v, want 30s", opt.OperationTimeout)
+	}
+	opt = ParseOption("url=https://minio.local:9000,request_deadline=2m")
+	if opt.OperationTimeout != 2*time.Minute {
+		t.Fatalf("request_deadline alias gave %v, want 2m", opt.OperationTimeout)
+	}
+	// An explicit 0s removes the bound; it is stored negative so
+	// WithDefaults doesn't mistake it for an unset field.
+	opt = ParseOption("url=https://minio.local:9000,op_timeout=0s")
+	if opt.OperationTimeout >= 0 {
+		t.Fatalf("op_timeout=0s gave %v, want a negative no-bound sentinel", opt.OperationTimeout)
+	}
+	if got := opt.WithDefaults(); got.OperationTimeout != opt.OperationTimeout {
+		t.Fatalf("WithDefaults turned the removed bound into %v", got.OperationTimeout)
+	}
+}
+
+func TestWithDefaultsFillsEmptyFields(t *testing.T) {
+	var zero Option
+	opt := zero.WithDefaults()
+	if opt.Region != defaultOption.Region || opt.AttrTimeout != defaultOption.AttrTimeout ||
+		opt.OperationTimeout != defaultOption.OperationTimeout || opt.CacheMaxSize != defaultOption.CacheMaxSize {
+		t.Fatalf("WithDefaults = %+v, want defaults filled", opt)
+	}
+	set := Option{Region: "eu-west-1", AttrTimeout: time.Minute, OperationTimeout: -1}
+	opt = set.WithDefaults()
+	if opt.Region != "eu-west-1" || opt.AttrTimeout != time.Minute || opt.OperationTimeout != -1 {
+		t.Fatalf("WithDefaults = %+v, want explicit values kept", opt)
+	}
+}
+
+func TestValidateRejectsClearlyWrongOptions(t *testing.T) {
+	good := defaultOption
+	good.URL = "https://minio.local:9000"
+	if err := good.Validate(); err != nil {
+		t.Fatalf("Validate of a sane option: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(*Option)
+	}{
+		{"missing url", func(o *Option) { o.URL = "" }},
+		{"bad scheme", func(o *Option) { o.URL = "ftp://host" }},
+		{"negative concurrency", func(o *Option) { o.Concurrency = -1 }},
+		{"negative upload concurrency", func(o *Option) { o.UploadConcurrency = -1 }},
+		{"unknown acl", func(o *Option) { o.ACL = "world-writable" }},
+		{"cache mode without dir", func(o *Option) { o.CacheMode = "reads" }},
+	}
+	for _, tc := range cases {
+		opt := good
+		tc.mutate(&opt)
+		if err := opt.Validate(); err == nil {
+			t.Errorf("Validate accepted %s", tc.name)
+		}
+	}
+
+	// Anonymous mounts are the one case that may omit the endpoint.
+	anon := defaultOption
+	anon.Anonymous = true
+	if err := anon.Validate(); err != nil {
+		t.Fatalf("Validate of anonymous option without url: %v", err)
+	}
+}
+
+func TestTransportTweaks(t *testing.T) {
+	opt := defaultOption
+	if opt.transportTweaks() != nil {
+		t.Fatal("default options produced transport tweaks, want none")
+	}
+
+	opt.HTTPProxy = "http://proxy.corp:3128"
+	opt.MaxIdleConns = 64
+	opt.IdleConnTimeout = 45 * time.Second
+	tweak := opt.transportTweaks()
+	if tweak == nil {
+		t.Fatal("transportTweaks = nil with proxy and pool options set")
+	}
+	tr := &http.Transport{}
+	tweak(tr)
+	if tr.Proxy == nil {
+		t.Fatal("proxy not applied to the transport")
+	}
+	u, err := tr.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "minio.local:9000"}})
+	if err != nil || u == nil || u.Host != "proxy.corp:3128" {
+		t.Fatalf("proxy resolves to %v, %v; want proxy.corp:3128", u, err)
+	}
+	if tr.MaxIdleConns != 64 || tr.MaxIdleConnsPerHost != 64 {
+		t.Fatalf("idle conns = %d/%d, want 64/64", tr.MaxIdleConns, tr.MaxIdleConnsPerHost)
+	}
+	if tr.IdleConnTimeout != 45*time.Second {
+		t.Fatalf("idle timeout = %v, want 45s", tr.IdleConnTimeout)
+	}
+}
+
+// TestConnectionStringRoundTrip serializes a well-populated Option and
+// checks ParseOption reproduces it field for field.
+func TestConnectionStringRoundTrip(t *testing.T) {
+	opt := defaultOption
+	opt.URL = "https://minio.local:9000"
+	opt.Region = "eu-central-1"
+	opt.AccessKey = "AK"
+	opt.SecretKey = "SK"
+	opt.UserID = "svc"
+	opt.AttrTimeout = time.Minute
+	opt.CacheDir = "/var/cache/s3fs"
+	opt.CacheMode = "full"
+	opt.PartSize = 16 * 1024 * 1024
+	opt.ACL = "private"
+	opt.MetadataPrefix = "team-"
+	opt.AllowEmptyFiles = false
+	opt.OperationTimeout = -1
+	opt.ReadOnly = true
+	opt.FileMode = 0444
+	opt.UID = 1234
+	opt.HTTPProxy = "http://proxy.corp:3128"
+	opt.IdleConnTimeout = 45 * time.Second
+
+	got := ParseOption(opt.ConnectionString())
+	if got != opt {
+		t.Fatalf("round trip changed the option:\n got %+v\nwant %+v", got, opt)
+	}
+
+	// The defaults serialize to nothing and parse back to themselves.
+	empty := defaultOption
+	if s := empty.ConnectionString(); s != "" {
+		t.Fatalf("defaults serialized to %q, want empty", s)
+	}
+	if got := ParseOption(""); got != defaultOption {
+		t.Fatalf("ParseOption(\"\") = %+v, want defaults", got)
+	}
+}
@@ -0,0 +1,452 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// This file builds the IAM-style policy documents used to share buckets
+// and directories between users. The builder tracks the bucket owner,
+// bucket-wide shares and per-directory shares, and renders the matching
+// policy JSON for PutBucketPolicy.
+
+const policyVersion = "2012-10-17"
+
+// Action sets granted to principals, split between actions on the bucket
+// (listing) and actions on the objects under a resource. Write-only is the
+// drop-box case: principals may upload but neither list nor read.
+var (
+	roObjActionSet    = []string{"s3:GetObject"}
+	woObjActionSet    = []string{"s3:PutObject"}
+	rwObjActionSet    = []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject"}
+	adminObjActionSet = []string{"s3:*"}
+	roDirActionSet    = []string{"s3:ListBucket"}
+	woDirActionSet    = []string{"s3:ListBucketMultipartUploads"}
+	rwDirActionSet    = []string{"s3:ListBucket", "s3:ListBucketMultipartUploads"}
+	adminDirActionSet = []string{"s3:*"}
+)
+
+// Perm selects the action sets granted to a share principal.
+type Perm string
+
+const (
+	PermRO    Perm = "ro"
+	PermWO    Perm = "wo"
+	PermRW    Perm = "rw"
+	PermAdmin Perm = "admin"
+)
+
+// actionSets returns the object and bucket action sets for a permission
+// level, defaulting unknown levels to read-only.
+func (perm Perm) actionSets() (obj, dir []string) {
+	switch perm {
+	case PermWO:
+		return woObjActionSet, woDirActionSet
+	case PermRW:
+		return rwObjActionSet, rwDirActionSet
+	case PermAdmin:
+		return adminObjActionSet, adminDirActionSet
+	}
+	return roObjActionSet, roDirActionSet
+}
+
+// share pairs a principal with its permission level.
+type share struct {
+	user string
+	perm Perm
+}
+
+// Statement is one statement of a bucket policy document.
+type Statement struct {
+	Sid       string                         `json:"Sid,omitempty"`
+	Effect    string                         `json:"Effect"`
+	Principal map[string][]string            `json:"Principal"`
+	Action    []string                       `json:"Action"`
+	Resource  []string                       `json:"Resource"`
+	Condition map[string]map[string][]string `json:"Condition,omitempty"`
+}
+
+// policyDocument is the marshalled form of a BucketPolicy.
+type policyDocument struct {
+	Version   string      `json:"Version"`
+	Statement []Statement `json:"Statement"`
+}
+
+// DirPolicy holds the shares of one directory (key prefix) in a bucket.
+// It is kept by pointer in BucketPolicy.dirs so additions are visible
+// without writing the value back into the map.
+type DirPolicy struct {
+	dir    string
+	owner  string
+	shares []share
+}
+
+// BucketPolicy accumulates the owner and shares of one bucket.
+type BucketPolicy struct {
+	bucket string
+	owner  string
+	// shares lists principals with access to the whole bucket.
+	shares []share
+	// dirs maps directory prefixes to their per-directory shares.
+	dirs map[string]*DirPolicy
+	// sourceIPs, when non-empty, restricts every statement to requests
+	// from these CIDR ranges.
+	sourceIPs []string
+	// denies are explicit Deny statements, rendered ahead of the allows.
+	denies []Statement
+}
+
+// NewBucketPolicy starts a policy for bucket owned by owner.
+func NewBucketPolicy(bucket, owner string) *BucketPolicy {
+	return &BucketPolicy{
+		bucket: bucket,
+		owner:  owner,
+		dirs:   make(map[string]*DirPolicy),
+	}
+}
+
+// AddShare grants user access to the whole bucket at the given permission
+// level.
+func (p *BucketPolicy) AddShare(user string, perm Perm) {
+	p.shares = append(p.shares, share{user: user, perm: perm})
+}
+
+// AddOwnDir records dir as owned by owner, creating its entry if needed.
+func (p *BucketPolicy) AddOwnDir(dir, owner string) {
+	d := p.dir(dir)
+	d.owner = owner
+}
+
+// AddShareDir grants user access to dir only at the given permission
+// level.
+func (p *BucketPolicy) AddShareDir(dir, user string, perm Perm) {
+	d := p.dir(dir)
+	d.shares = append(d.shares, share{user: user, perm: perm})
+}
+
+// AddDeny appends an explicit Deny of the given actions on resource for
+// user. Per IAM evaluation an explicit Deny beats any Allow, so this is how
+// "share read-only but never delete" style policies are expressed. An
+// empty resource denies on the whole bucket's objects.
+func (p *BucketPolicy) AddDeny(user string, actions []string, resource string) {
+	if resource == "" {
+		resource = p.bucketARN() + "/*"
+	}
+	p.denies = append(p.denies, Statement{
+		Effect:    "Deny",
+		Principal: map[string][]string{"AWS": {userARN(user)}},
+		Action:    actions,
+		Resource:  []string{resource},
+	})
+}
+
+// WithSourceIPRestriction limits every statement of the policy to requests
+// originating from the given CIDR ranges, rendered as an IpAddress
+// condition on aws:SourceIp. The CIDRs are validated up front so a typo
+// fails here rather than as an opaque MalformedPolicy from the backend.
+func (p *BucketPolicy) WithSourceIPRestriction(cidrs []string) error {
+	for _, c := range cidrs {
+		if _, _, err := net.ParseCIDR(c); err != nil {
+			return fmt.Errorf("bad source CIDR %q: %w", c, err)
+		}
+	}
+	p.sourceIPs = append(p.sourceIPs, cidrs...)
+	return nil
+}
+
+// dir returns the DirPolicy for dir, creating it on first use. The map is
+// initialised lazily as well so a zero-value BucketPolicy doesn't panic
+// with assignment to entry in nil map.
+func (p *BucketPolicy) dir(dir string) *DirPolicy {
+	if p.dirs == nil {
+		p.dirs = make(map[string]*DirPolicy)
+	}
+	d, ok := p.dirs[dir]
+	if !ok {
+		d = &DirPolicy{dir: dir}
+		p.dirs[dir] = d
+	}
+	return d
+}
+
+// userARN renders the principal ARN for a user name.
+func userARN(user string) string {
+	return "arn:aws:iam:::user/" + user
+}
+
+func (p *BucketPolicy) bucketARN() string {
+	return "arn:aws:s3:::" + p.bucket
+}
+
+// statements renders the accumulated shares as policy statements, denies
+// first.
+func (p *BucketPolicy) statements() []Statement {
+	stmts := append([]Statement(nil), p.denies...)
+	allObjects := []string{p.bucketARN() + "/*"}
+	bucketOnly := []string{p.bucketARN()}
+	if p.owner != "" {
+		stmts = append(stmts,
+			Statement{
+				Effect:    "Allow",
+				Principal: map[string][]string{"AWS": {userARN(p.owner)}},
+				Action:    rwDirActionSet,
+				Resource:  bucketOnly,
+			},
+			Statement{
+				Effect:    "Allow",
+				Principal: map[string][]string{"AWS": {userARN(p.owner)}},
+				Action:    rwObjActionSet,
+				Resource:  allObjects,
+			})
+	}
+	for _, s := range p.shares {
+		objActions, dirActions := s.perm.actionSets()
+		stmts = append(stmts,
+			Statement{
+				Effect:    "Allow",
+				Principal: map[string][]string{"AWS": {userARN(s.user)}},
+				Action:    dirActions,
+				Resource:  bucketOnly,
+			},
+			Statement{
+				Effect:    "Allow",
+				Principal: map[string][]string{"AWS": {userARN(s.user)}},
+				Action:    objActions,
+				Resource:  allObjects,
+			})
+	}
+	for _, d := range p.dirs {
+		dirObjects := []string{fmt.Sprintf("%s/%s/*", p.bucketARN(), d.dir)}
+		shares := d.shares
+		if d.owner != "" {
+			shares = append([]share{{user: d.owner, perm: PermRW}}, shares...)
+		}
+		for _, s := range shares {
+			objActions, dirActions := s.perm.actionSets()
+			stmts = append(stmts,
+				Statement{
+					Effect:    "Allow",
+					Principal: map[string][]string{"AWS": {userARN(s.user)}},
+					Action:    dirActions,
+					Resource:  bucketOnly,
+					Condition: map[string]map[string][]string{
+						"StringLike": {"s3:prefix": {d.dir + "/*"}},
+					},
+				},
+				Statement{
+					Effect:    "Allow",
+					Principal: map[string][]string{"AWS": {userARN(s.user)}},
+					Action:    objActions,
+					Resource:  dirObjects,
+				})
+		}
+	}
+	if len(p.sourceIPs) > 0 {
+		for i := range stmts {
+			if stmts[i].Condition == nil {
+				stmts[i].Condition = make(map[string]map[string][]string)
+			}
+			stmts[i].Condition["IpAddress"] = map[string][]string{
+				"aws:SourceIp": p.sourceIPs,
+			}
+		}
+	}
+	return stmts
+}
+
+// GenerateBucketPolicy builds the policy document for bucket owned by
+// owner with the given bucket-wide shares and their permission levels.
+func GenerateBucketPolicy(bucket, owner string, shares map[string]Perm) *BucketPolicy {
+	p := NewBucketPolicy(bucket, owner)
+	for user, perm := range shares {
+		p.AddShare(user, perm)
+	}
+	return p
+}
+
+// equalActionSet reports whether the two action lists contain the same
+// actions regardless of order.
+func equalActionSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, x := range a {
+		set[x] = true
+	}
+	for _, x := range b {
+		if !set[x] {
+			return false
+		}
+	}
+	return true
+}
+
+// permFromObjActions maps an object action list back to the permission
+// level that generates it, for parsing policies we wrote ourselves.
+func permFromObjActions(actions []string) (Perm, bool) {
+	switch {
+	case equalActionSet(actions, roObjActionSet):
+		return PermRO, true
+	case equalActionSet(actions, woObjActionSet):
+		return PermWO, true
+	case equalActionSet(actions, rwObjActionSet):
+		return PermRW, true
+	case equalActionSet(actions, adminObjActionSet):
+		return PermAdmin, true
+	}
+	return PermRO, false
+}
+
+// principalUser extracts the user name from a statement's principal, or
+// "" when the principal isn't a single IAM user ARN.
+func principalUser(s Statement) string {
+	users := s.Principal["AWS"]
+	if len(users) != 1 {
+		return ""
+	}
+	return strings.TrimPrefix(users[0], "arn:aws:iam:::user/")
+}
+
+// ParseBucketPolicy reads a policy document produced by ToString back
+// into a BucketPolicy, recovering the owner, shares, per-directory
+// shares, denies and source IP restriction. The first principal holding
+// read-write over the whole bucket (or a directory) is taken as its
+// owner, matching the order statements renders them in. Statements this
+// module never generates are rejected.
+func ParseBucketPolicy(doc string) (*BucketPolicy, error) {
+	var parsed policyDocument
+	if err := json.Unmarshal([]byte(doc), &parsed); err != nil {
+		return nil, fmt.Errorf("parse bucket policy: %w", err)
+	}
+	var p *BucketPolicy
+	policyFor := func(resource string) (*BucketPolicy, error) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(resource, "arn:aws:s3:::"), "/")
+		if p == nil {
+			p = NewBucketPolicy(name, "")
+		} else if p.bucket != name {
+			return nil, fmt.Errorf("parse bucket policy: statements name both %q and %q", p.bucket, name)
+		}
+		return p, nil
+	}
+	for _, s := range parsed.Statement {
+		if len(s.Resource) == 0 {
+			return nil, fmt.Errorf("parse bucket policy: statement without resources")
+		}
+		if _, err := policyFor(s.Resource[0]); err != nil {
+			return nil, err
+		}
+		// The IP restriction is stamped onto every statement; record it
+		// once and ignore it during further matching.
+		if ips := s.Condition["IpAddress"]["aws:SourceIp"]; len(ips) > 0 && len(p.sourceIPs) == 0 {
+			p.sourceIPs = append(p.sourceIPs, ips...)
+		}
+		if s.Effect == "Deny" {
+			delete(s.Condition, "IpAddress")
+			if len(s.Condition) == 0 {
+				s.Condition = nil
+			}
+			p.denies = append(p.denies, s)
+			continue
+		}
+		user := principalUser(s)
+		if user == "" {
+			return nil, fmt.Errorf("parse bucket policy: statement with unsupported principal %v", s.Principal)
+		}
+		switch {
+		case prefixCondition(s) != "":
+			// Directory listing statement; the matching object statement
+			// carries the permission, so only ensure the dir exists.
+			p.dir(strings.TrimSuffix(prefixCondition(s), "/*"))
+		case strings.HasSuffix(s.Resource[0], "/*"):
+			perm, _ := permFromObjActions(s.Action)
+			if s.Resource[0] == p.bucketARN()+"/*" {
+				// Whole-bucket object statement: the first read-write
+				// principal is the owner, everyone after is a share.
+				// Matched exactly — trimming the prefix would leave "*"
+				// here and misread the statement as a directory grant.
+				if perm == PermRW && p.owner == "" {
+					p.owner = user
+				} else {
+					p.AddShare(user, perm)
+				}
+			} else {
+				rest := strings.TrimSuffix(strings.TrimPrefix(s.Resource[0], p.bucketARN()+"/"), "/*")
+				dir := strings.TrimSuffix(rest, "/")
+				d := p.dir(dir)
+				if perm == PermRW && d.owner == "" {
+					d.owner = user
+				} else {
+					p.AddShareDir(dir, user, perm)
+				}
+			}
+		}
+		// Bucket-only listing statements without a prefix condition are
+		// the dir-action half of a bucket share; nothing to record.
+	}
+	if p == nil {
+		return nil, fmt.Errorf("parse bucket policy: no statements")
+	}
+	return p, nil
+}
+
+// prefixCondition returns the s3:prefix pattern of a statement's
+// StringLike condition, or "".
+func prefixCondition(s Statement) string {
+	prefixes := s.Condition["StringLike"]["s3:prefix"]
+	if len(prefixes) != 1 {
+		return ""
+	}
+	return prefixes[0]
+}
+
+// validateStatements checks for the structural mistakes the backend would
+// only answer with an opaque MalformedPolicy: empty action or resource
+// lists, unknown effects, missing principals and resources that aren't s3
+// ARNs. Catching them here saves a network round-trip per debugging
+// iteration.
+func validateStatements(stmts []Statement) error {
+	if len(stmts) == 0 {
+		return fmt.Errorf("policy has no statements")
+	}
+	for i, s := range stmts {
+		if s.Effect != "Allow" && s.Effect != "Deny" {
+			return fmt.Errorf("statement %d: unknown effect %q", i, s.Effect)
+		}
+		if len(s.Action) == 0 {
+			return fmt.Errorf("statement %d: no actions", i)
+		}
+		if len(s.Principal) == 0 {
+			return fmt.Errorf("statement %d: no principal", i)
+		}
+		if len(s.Resource) == 0 {
+			return fmt.Errorf("statement %d: no resources", i)
+		}
+		for _, r := range s.Resource {
+			if !strings.HasPrefix(r, "arn:aws:s3:::") {
+				return fmt.Errorf("statement %d: %q is not an s3 ARN", i, r)
+			}
+		}
+	}
+	return nil
+}
+
+// ToString validates the policy and renders it as the JSON document S3
+// expects.
+func (p *BucketPolicy) ToString() (string, error) {
+	stmts := p.statements()
+	if err := validateStatements(stmts); err != nil {
+		return "", err
+	}
+	doc := policyDocument{
+		Version:   policyVersion,
+		Statement: stmts,
+	}
+	out, err := json.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
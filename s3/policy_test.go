@@ -0,0 +1,117 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBucketPolicyBuild(t *testing.T) {
+	p := NewBucketPolicy("data", "alice")
+	p.AddOwnDir("homes/alice", "alice")
+	p.AddOwnDir("homes/bob", "bob")
+	p.AddShare("dave", PermRW)
+	p.AddShareDir("homes/alice", "bob", PermRO)
+
+	out, err := p.ToString()
+	if err != nil {
+		t.Fatalf("ToString: %v", err)
+	}
+	for _, want := range []string{
+		userARN("alice"), userARN("bob"), userARN("dave"),
+		"arn:aws:s3:::data/homes/alice/*",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("policy JSON misses %q: %s", want, out)
+		}
+	}
+
+	// A zero value must work too, not just NewBucketPolicy results.
+	var zero BucketPolicy
+	zero.bucket = "data"
+	zero.AddOwnDir("scratch", "alice")
+	if _, err := zero.ToString(); err != nil {
+		t.Fatalf("ToString on zero value: %v", err)
+	}
+}
+
+func TestWriteOnlyShare(t *testing.T) {
+	p := NewBucketPolicy("inbox", "")
+	p.AddShare("drop", PermWO)
+	out, err := p.ToString()
+	if err != nil {
+		t.Fatalf("ToString: %v", err)
+	}
+	if !strings.Contains(out, "s3:PutObject") {
+		t.Errorf("write-only share can't upload: %s", out)
+	}
+	if strings.Contains(out, "s3:GetObject") {
+		t.Errorf("write-only share can read: %s", out)
+	}
+}
+
+func TestAddDeny(t *testing.T) {
+	p := NewBucketPolicy("data", "alice")
+	p.AddShare("bob", PermRO)
+	p.AddDeny("bob", []string{"s3:DeleteObject"}, "")
+
+	out, err := p.ToString()
+	if err != nil {
+		t.Fatalf("ToString: %v", err)
+	}
+	denyIdx := strings.Index(out, `"Effect":"Deny"`)
+	if denyIdx < 0 {
+		t.Fatalf("policy JSON misses the Deny statement: %s", out)
+	}
+	// The deny must come before the first Allow and name bob.
+	allowIdx := strings.Index(out, `"Effect":"Allow"`)
+	if allowIdx < 0 || denyIdx > allowIdx {
+		t.Errorf("Deny rendered after Allow: %s", out)
+	} else if !strings.Contains(out[denyIdx:allowIdx], userARN("bob")) {
+		t.Errorf("Deny statement misses bob's principal: %s", out)
+	}
+}
+
+func TestSourceIPRestriction(t *testing.T) {
+	p := NewBucketPolicy("data", "alice")
+	if err := p.WithSourceIPRestriction([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("valid CIDR rejected: %v", err)
+	}
+	if err := p.WithSourceIPRestriction([]string{"not-a-cidr"}); err == nil {
+		t.Fatal("invalid CIDR accepted")
+	}
+	out, err := p.ToString()
+	if err != nil {
+		t.Fatalf("ToString: %v", err)
+	}
+	if !strings.Contains(out, "aws:SourceIp") || !strings.Contains(out, "10.0.0.0/8") {
+		t.Errorf("policy JSON misses the source IP condition: %s", out)
+	}
+}
+
+func TestAddShareDirStoresShares(t *testing.T) {
+	p := NewBucketPolicy("data", "alice")
+	p.AddShareDir("projects", "bob", PermRW)
+	p.AddShareDir("projects", "carol", PermRO)
+
+	d, ok := p.dirs["projects"]
+	if !ok {
+		t.Fatal("AddShareDir didn't create the dir entry")
+	}
+	if len(d.shares) != 2 {
+		t.Fatalf("dir shares = %v, want [bob carol]", d.shares)
+	}
+	if len(p.shares) != 0 {
+		t.Fatalf("bucket-level shares = %v, want none", p.shares)
+	}
+
+	out, err := p.ToString()
+	if err != nil {
+		t.Fatalf("ToString: %v", err)
+	}
+	if !strings.Contains(out, userARN("bob")) {
+		t.Errorf("policy JSON misses bob's principal: %s", out)
+	}
+	if !strings.Contains(out, "arn:aws:s3:::data/projects/*") {
+		t.Errorf("policy JSON misses the dir-level resource: %s", out)
+	}
+}
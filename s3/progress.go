@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// Progress reporting for UIs wrapping this client: the ...WithProgress
+// variants behave exactly like their plain counterparts but invoke
+// onProgress as bytes move. The callback runs on transfer goroutines —
+// several at once during multipart transfers — so it must be cheap and
+// must not call back into the client.
+
+// progressReader counts bytes flowing through an io.Reader and reports
+// each step to the callback.
+type progressReader struct {
+	r          io.Reader
+	done       atomic.Int64
+	total      int64
+	onProgress func(done, total int64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.onProgress(pr.done.Add(int64(n)), pr.total)
+	}
+	return n, err
+}
+
+// progressWriterAt does the same for the downloader's io.WriterAt sink.
+// Parts land out of order, so done tracks the byte count, not a frontier
+// offset.
+type progressWriterAt struct {
+	w          io.WriterAt
+	done       atomic.Int64
+	total      int64
+	onProgress func(done, total int64)
+}
+
+func (pw *progressWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := pw.w.WriteAt(p, off)
+	if n > 0 {
+		pw.onProgress(pw.done.Add(int64(n)), pw.total)
+	}
+	return n, err
+}
+
+// UploadObjectWithProgress is UploadObject with live byte counts: the
+// body is wrapped in a counting reader that calls onProgress(done, size)
+// as it is consumed. size is only echoed to the callback — pass a
+// negative value when the length is unknown and render the count alone.
+func (c *s3Client) UploadObjectWithProgress(ctx context.Context, userID, bucket, path string, reader io.Reader, size int64, onProgress func(done, total int64), opts ...PutOption) error {
+	if onProgress != nil {
+		reader = &progressReader{r: reader, total: size, onProgress: onProgress}
+	}
+	return c.UploadObject(ctx, userID, bucket, path, reader, opts...)
+}
+
+// DownloadToWithProgress is DownloadTo with live byte counts. The total
+// passed to onProgress is the object's size, fetched with one HeadObject
+// up front; if that fails the download proceeds with total -1.
+func (c *s3Client) DownloadToWithProgress(ctx context.Context, userID, bucket, path string, w io.WriterAt, onProgress func(done, total int64)) (int64, error) {
+	if onProgress != nil {
+		total := int64(-1)
+		if obj, err := c.HeadObject(ctx, userID, bucket, path); err == nil {
+			total = obj.Size
+		}
+		w = &progressWriterAt{w: w, total: total, onProgress: onProgress}
+	}
+	return c.DownloadTo(ctx, userID, bucket, path, w)
+}
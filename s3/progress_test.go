@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestUploadObjectWithProgress(t *testing.T) {
+	cli := fakeClient(t, "progress")
+	ctx := context.Background()
+	body := bytes.Repeat([]byte("x"), 100*1024)
+
+	var mu sync.Mutex
+	var last, calls int64
+	err := cli.UploadObjectWithProgress(ctx, "test", "progress", "up", bytes.NewReader(body), int64(len(body)),
+		func(done, total int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if done < last {
+				t.Errorf("progress went backwards: %d after %d", done, last)
+			}
+			last = done
+			if total != int64(len(body)) {
+				t.Errorf("total = %d, want %d", total, len(body))
+			}
+		})
+	if err != nil {
+		t.Fatalf("UploadObjectWithProgress: %v", err)
+	}
+	if last != int64(len(body)) || calls == 0 {
+		t.Fatalf("final progress %d after %d calls, want %d", last, calls, len(body))
+	}
+}
+
+func TestDownloadToWithProgress(t *testing.T) {
+	cli := fakeClient(t, "progress-dl")
+	ctx := context.Background()
+	body := bytes.Repeat([]byte("y"), 64*1024)
+	if err := cli.PutObject(ctx, "test", "progress-dl", "down", body); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	var mu sync.Mutex
+	var sum int64
+	totals := map[int64]bool{}
+	buf := manager.NewWriteAtBuffer(make([]byte, len(body)))
+	n, err := cli.DownloadToWithProgress(ctx, "test", "progress-dl", "down", buf,
+		func(done, total int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			// Parts land concurrently, so only the high-water mark is
+			// meaningful here.
+			if done > sum {
+				sum = done
+			}
+			totals[total] = true
+		})
+	if err != nil {
+		t.Fatalf("DownloadToWithProgress: %v", err)
+	}
+	if n != int64(len(body)) || sum != n {
+		t.Fatalf("downloaded %d, final progress %d, want %d", n, sum, len(body))
+	}
+	if !totals[int64(len(body))] || len(totals) != 1 {
+		t.Fatalf("reported totals %v, want only %d", totals, len(body))
+	}
+	if !bytes.Equal(buf.Bytes(), body) {
+		t.Fatal("downloaded content differs")
+	}
+}
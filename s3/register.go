@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"github.com/ThierryZhou/go-s3fs/fs"
+)
+
+// init makes the s3 backend discoverable through the fs registry, so
+// callers can select it by the "s3" type prefix instead of linking the
+// constructor directly.
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "s3",
+		Description: "S3-compatible object stores (AWS, MinIO, Ceph)",
+		NewFs: func(name, config string) (interface{}, error) {
+			return NewS3FileSystem(name, config)
+		},
+		Options: []fs.Option{{
+			Name:     "url",
+			Help:     "Endpoint of the object store. Required unless anonymous; several gateways may be listed separated by ';'.",
+			Required: true,
+			Examples: []fs.OptionExample{
+				{Value: "https://minio.local:9000", Help: "Self-hosted MinIO"},
+				{Value: "https://ceph-rgw.example.com", Help: "Ceph RADOS gateway"},
+				{Value: "https://s3.us-east-1.amazonaws.com", Help: "AWS S3"},
+			},
+		}, {
+			Name:    "region",
+			Help:    "Signing region. Self-hosted gateways usually accept any value.",
+			Default: defaultOption.Region,
+		}, {
+			Name: "accesskey",
+			Help: "Access key ID. Leave empty to use the environment or shared credentials.",
+		}, {
+			Name:       "secretkey",
+			Help:       "Secret access key paired with the access key.",
+			IsPassword: true,
+		}, {
+			Name: "anonymous",
+			Help: "Send unsigned requests, for world-readable buckets.",
+			Examples: []fs.OptionExample{
+				{Value: "true", Help: "No credentials at all"},
+			},
+		}, {
+			Name: "acl",
+			Help: "Canned ACL applied to uploads and created buckets. Empty keeps the backend's default.",
+			Examples: []fs.OptionExample{
+				{Value: "private", Help: "Owner-only access"},
+				{Value: "public-read", Help: "World-readable objects"},
+			},
+		}, {
+			Name: "cache_mode",
+			Help: "Caching strategy: off, reads, writes or full. reads and full need cache_dir.",
+			Examples: []fs.OptionExample{
+				{Value: "off", Help: "Stream everything, nothing ever stale"},
+				{Value: "full", Help: "Disk cache plus write-back, fastest"},
+			},
+		}, {
+			Name: "cache_dir",
+			Help: "Directory for the local read cache.",
+		}, {
+			Name: "read_only",
+			Help: "Refuse every mutation with EROFS.",
+		}},
+	})
+}
@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/ThierryZhou/go-s3fs/fs"
+)
+
+func TestBackendIsRegistered(t *testing.T) {
+	info, err := fs.Find("s3")
+	if err != nil {
+		t.Fatalf("Find(s3): %v", err)
+	}
+	if info.Name != "s3" || info.NewFs == nil {
+		t.Fatalf("RegInfo = %+v, want name s3 with a constructor", info)
+	}
+	found := false
+	for _, name := range fs.Backends() {
+		if name == "s3" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Backends() = %v, want s3 listed", fs.Backends())
+	}
+
+	if _, err := fs.Find("gopher"); err == nil {
+		t.Fatal("Find of an unregistered backend succeeded")
+	}
+}
+
+// TestBackendOptionsDescribeSetup checks the registered option list is
+// usable by an interactive prompt: every name parses, the endpoint is
+// required and the secret is masked.
+func TestBackendOptionsDescribeSetup(t *testing.T) {
+	info := fs.MustFind("s3")
+	byName := make(map[string]fs.Option, len(info.Options))
+	for _, o := range info.Options {
+		if o.Name == "" || o.Help == "" {
+			t.Fatalf("option %+v is missing name or help", o)
+		}
+		byName[o.Name] = o
+	}
+	if !byName["url"].Required {
+		t.Fatal("url option is not marked required")
+	}
+	if !byName["secretkey"].IsPassword {
+		t.Fatal("secretkey option is not marked as a password")
+	}
+	if byName["region"].Default != defaultOption.Region {
+		t.Fatalf("region default = %q, want %q", byName["region"].Default, defaultOption.Region)
+	}
+	// Every advertised key must be one the connection-string parser
+	// understands, or the answers would be silently dropped.
+	known := map[string]bool{}
+	for _, key := range []string{"url", "region", "accesskey", "secretkey",
+		"anonymous", "acl", "cache_mode", "cache_dir", "read_only"} {
+		known[key] = true
+	}
+	for name := range byName {
+		if !known[name] {
+			t.Fatalf("option %q has no connection-string spelling", name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package s3
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// ExponentialJitterBackoff computes retry delays growing exponentially with
+// the attempt number, with +/-20% jitter to avoid thundering herds.
+type ExponentialJitterBackoff struct {
+	minDelay   time.Duration
+	maxBackoff time.Duration
+	// mu guards rnd: the retryer is shared by every in-flight operation
+	// of a client, and *rand.Rand is not safe for concurrent use.
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewExponentialJitterBackoff returns an ExponentialJitterBackoff capped at
+// maxBackoff, jittered from a freshly seeded source.
+func NewExponentialJitterBackoff(maxBackoff time.Duration) *ExponentialJitterBackoff {
+	return NewExponentialJitterBackoffWithRand(maxBackoff, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// NewExponentialJitterBackoffWithRand is like NewExponentialJitterBackoff
+// but draws jitter from rnd, so tests can fix the seed and assert the
+// computed delays.
+func NewExponentialJitterBackoffWithRand(maxBackoff time.Duration, rnd *rand.Rand) *ExponentialJitterBackoff {
+	return &ExponentialJitterBackoff{
+		minDelay:   20 * time.Millisecond,
+		maxBackoff: maxBackoff,
+		rnd:        rnd,
+	}
+}
+
+// noRetry4xx implements retry.IsErrorRetryable: client errors are
+// permanent — wrong credentials or a missing bucket won't heal with
+// backoff — so any 4xx except 429 Too Many Requests fails immediately
+// instead of burning through 20 attempts. Everything else is left to
+// the SDK's default classification.
+type noRetry4xx struct{}
+
+func (noRetry4xx) IsErrorRetryable(err error) aws.Ternary {
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) {
+		if code := re.HTTPStatusCode(); code >= 400 && code < 500 && code != 429 {
+			return aws.FalseTernary
+		}
+	}
+	switch apiErrorCode(err) {
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch",
+		"NoSuchBucket", "NoSuchKey", "NotFound":
+		return aws.FalseTernary
+	}
+	return aws.UnknownTernary
+}
+
+// BackoffDelay implements retry.BackoffDelayer.
+func (j *ExponentialJitterBackoff) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	// 80%..120% of the exponential delay. The math stays in float64 so
+	// large attempt numbers saturate at the cap instead of overflowing
+	// int64 and going negative.
+	j.mu.Lock()
+	jitter := float64(j.rnd.Intn(40)+80) / 100
+	j.mu.Unlock()
+	backoff := float64(j.minDelay) * math.Pow(3, float64(attempt)) * jitter
+	if backoff > float64(j.maxBackoff) {
+		return j.maxBackoff, nil
+	}
+	return time.Duration(backoff), nil
+}
@@ -0,0 +1,86 @@
+package s3
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+)
+
+func TestNoRetry4xxClassification(t *testing.T) {
+	r := noRetry4xx{}
+	for _, code := range []string{"AccessDenied", "NoSuchBucket", "SignatureDoesNotMatch"} {
+		err := &smithy.GenericAPIError{Code: code, Message: code}
+		if got := r.IsErrorRetryable(err); got != aws.FalseTernary {
+			t.Errorf("IsErrorRetryable(%s) = %v, want FalseTernary", code, got)
+		}
+	}
+	// Throttling and unclassified errors stay with the SDK's defaults.
+	throttle := &smithy.GenericAPIError{Code: "SlowDown", Message: "slow down"}
+	if got := r.IsErrorRetryable(throttle); got != aws.UnknownTernary {
+		t.Errorf("IsErrorRetryable(SlowDown) = %v, want UnknownTernary", got)
+	}
+	if got := r.IsErrorRetryable(errors.New("connection reset")); got != aws.UnknownTernary {
+		t.Errorf("IsErrorRetryable(transport error) = %v, want UnknownTernary", got)
+	}
+}
+
+func TestBackoffDelayDeterministicWithSeed(t *testing.T) {
+	a := NewExponentialJitterBackoffWithRand(5*time.Minute, rand.New(rand.NewSource(1)))
+	b := NewExponentialJitterBackoffWithRand(5*time.Minute, rand.New(rand.NewSource(1)))
+	for attempt := 0; attempt < 5; attempt++ {
+		da, err := a.BackoffDelay(attempt, nil)
+		if err != nil {
+			t.Fatalf("BackoffDelay(%d): %v", attempt, err)
+		}
+		db, _ := b.BackoffDelay(attempt, nil)
+		if da != db {
+			t.Fatalf("attempt %d: same seed gave %v and %v", attempt, da, db)
+		}
+	}
+}
+
+func TestBackoffDelayCappedAndMonotonic(t *testing.T) {
+	max := 5 * time.Minute
+	j := NewExponentialJitterBackoffWithRand(max, rand.New(rand.NewSource(7)))
+	// With +/-20% jitter consecutive attempts can wobble, but tripling
+	// the base guarantees each delay is at least double the previous
+	// one until the cap is reached (0.8*3 > 1.2*2).
+	var prev time.Duration
+	for attempt := 0; attempt <= 30; attempt++ {
+		d, err := j.BackoffDelay(attempt, nil)
+		if err != nil {
+			t.Fatalf("BackoffDelay(%d): %v", attempt, err)
+		}
+		if d < 0 {
+			t.Fatalf("attempt %d: negative delay %v", attempt, d)
+		}
+		if d > max {
+			t.Fatalf("attempt %d: delay %v exceeds cap %v", attempt, d, max)
+		}
+		if prev < max && d < prev {
+			t.Fatalf("attempt %d: delay %v dropped below previous %v before the cap", attempt, d, prev)
+		}
+		prev = d
+	}
+	if prev != max {
+		t.Fatalf("attempt 30 delay = %v, want capped at %v", prev, max)
+	}
+}
+
+func TestBackoffDelayJitterRange(t *testing.T) {
+	j := NewExponentialJitterBackoffWithRand(5*time.Minute, rand.New(rand.NewSource(42)))
+	base := 20 * time.Millisecond // minDelay * 3^0
+	for i := 0; i < 100; i++ {
+		d, err := j.BackoffDelay(0, nil)
+		if err != nil {
+			t.Fatalf("BackoffDelay: %v", err)
+		}
+		if d < base*80/100 || d >= base*120/100 {
+			t.Fatalf("delay %v outside 80%%..120%% of %v", d, base)
+		}
+	}
+}
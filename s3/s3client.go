@@ -0,0 +1,130 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// This file manages bucket shares: the policy-backed grants that let
+// users other than the owner access a bucket. Shares live entirely in
+// the bucket policy document, so every change is a read-modify-write of
+// that policy.
+
+// GetBucketPolicy fetches and parses the bucket's current policy. A
+// bucket without a policy returns (nil, nil).
+func (c *s3Client) GetBucketPolicy(ctx context.Context, bucket string) (*BucketPolicy, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetBucketPolicy(ctx, &s3v2.GetBucketPolicyInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if apiErrorCode(err) == "NoSuchBucketPolicy" {
+			return nil, nil
+		}
+		c.log.Errorf("get bucket policy %s error: %v", bucket, err)
+		return nil, fmt.Errorf("get bucket policy %s: %w", bucket, classify(err))
+	}
+	return ParseBucketPolicy(aws.ToString(out.Policy))
+}
+
+// listBucketShares returns the principals the bucket is shared with,
+// read from its policy. The owner is not a share.
+func (c *s3Client) listBucketShares(ctx context.Context, bucket string) ([]share, error) {
+	policy, err := c.GetBucketPolicy(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, nil
+	}
+	return policy.shares, nil
+}
+
+// CreateShare grants user a bucket-wide share at the given permission
+// level by rewriting the bucket policy. A bucket that has no policy yet
+// gets a fresh one owned by userID; sharing with the same user again
+// updates their permission level instead of stacking grants.
+func (c *s3Client) CreateShare(ctx context.Context, userID, bucket, user string, perm Perm) error {
+	policy, err := c.GetBucketPolicy(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		policy = NewBucketPolicy(bucket, userID)
+	}
+	updated := false
+	for i, s := range policy.shares {
+		if s.user == user {
+			policy.shares[i].perm = perm
+			updated = true
+			break
+		}
+	}
+	if !updated {
+		policy.AddShare(user, perm)
+	}
+	return c.PutBucketPolicy(ctx, bucket, policy)
+}
+
+// DeleteShare revokes user's bucket-wide share by rewriting the policy
+// without their principal. Directory shares for the user are removed as
+// well. A bucket without a policy, or a user without a share, is not an
+// error. When nothing grantable remains the policy is deleted outright,
+// since S3 rejects policies with no statements.
+func (c *s3Client) DeleteShare(ctx context.Context, userID, bucket, user string) error {
+	policy, err := c.GetBucketPolicy(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return nil
+	}
+	kept := policy.shares[:0]
+	for _, s := range policy.shares {
+		if s.user != user {
+			kept = append(kept, s)
+		}
+	}
+	policy.shares = kept
+	for _, d := range policy.dirs {
+		keptDir := d.shares[:0]
+		for _, s := range d.shares {
+			if s.user != user {
+				keptDir = append(keptDir, s)
+			}
+		}
+		d.shares = keptDir
+	}
+	if len(policy.statements()) == 0 {
+		ctx, cancel := c.opCtx(ctx)
+		defer cancel()
+		if _, err := c.cli.DeleteBucketPolicy(ctx, &s3v2.DeleteBucketPolicyInput{
+			Bucket: aws.String(bucket),
+		}); err != nil {
+			c.log.Errorf("delete bucket policy %s error: %v", bucket, err)
+			return fmt.Errorf("delete bucket policy %s: %w", bucket, classify(err))
+		}
+		c.invalidatePolicy(bucket)
+		return nil
+	}
+	return c.PutBucketPolicy(ctx, bucket, policy)
+}
+
+// deleteBucketShares revokes every share before a bucket is removed, so
+// no grant outlives the bucket in any external bookkeeping.
+func (c *s3Client) deleteBucketShares(ctx context.Context, userID, bucket string) error {
+	shares, err := c.listBucketShares(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	for _, s := range shares {
+		if err := c.DeleteShare(ctx, userID, bucket, s.user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"testing"
+)
+
+func shareUsers(shares []share) []string {
+	users := make([]string, len(shares))
+	for i, s := range shares {
+		users[i] = s.user
+	}
+	return users
+}
+
+func TestParseBucketPolicyRoundTrip(t *testing.T) {
+	p := GenerateBucketPolicy("proj", "alice", map[string]Perm{"bob": PermRO})
+	p.AddShareDir("incoming", "dan", PermWO)
+	doc, err := p.ToString()
+	if err != nil {
+		t.Fatalf("ToString: %v", err)
+	}
+
+	parsed, err := ParseBucketPolicy(doc)
+	if err != nil {
+		t.Fatalf("ParseBucketPolicy: %v", err)
+	}
+	if parsed.bucket != "proj" || parsed.owner != "alice" {
+		t.Fatalf("parsed bucket/owner = %q/%q, want proj/alice", parsed.bucket, parsed.owner)
+	}
+	if len(parsed.shares) != 1 || parsed.shares[0] != (share{user: "bob", perm: PermRO}) {
+		t.Fatalf("parsed shares = %+v, want bob ro", parsed.shares)
+	}
+	d := parsed.dirs["incoming"]
+	if d == nil || len(d.shares) != 1 || d.shares[0] != (share{user: "dan", perm: PermWO}) {
+		t.Fatalf("parsed dir shares = %+v, want dan wo on incoming", parsed.dirs)
+	}
+}
+
+func TestCreateShareBuildsAndUpdatesPolicy(t *testing.T) {
+	cli := fakeClient(t, "grants")
+	ctx := context.Background()
+
+	// No policy yet: sharing creates a fresh one owned by the caller.
+	if err := cli.CreateShare(ctx, "alice", "grants", "bob", PermRO); err != nil {
+		t.Fatalf("CreateShare: %v", err)
+	}
+	policy, err := cli.GetBucketPolicy(ctx, "grants")
+	if err != nil {
+		t.Fatalf("GetBucketPolicy: %v", err)
+	}
+	if policy == nil || policy.owner != "alice" {
+		t.Fatalf("policy owner = %+v, want alice", policy)
+	}
+	if len(policy.shares) != 1 || policy.shares[0] != (share{user: "bob", perm: PermRO}) {
+		t.Fatalf("shares = %+v, want bob ro", policy.shares)
+	}
+
+	// Sharing again with the same user updates the level in place.
+	if err := cli.CreateShare(ctx, "alice", "grants", "bob", PermRW); err != nil {
+		t.Fatalf("CreateShare upgrade: %v", err)
+	}
+	if policy, err = cli.GetBucketPolicy(ctx, "grants"); err != nil {
+		t.Fatalf("GetBucketPolicy: %v", err)
+	}
+	if len(policy.shares) != 1 || policy.shares[0].perm != PermRW {
+		t.Fatalf("shares after upgrade = %+v, want bob rw", policy.shares)
+	}
+}
+
+func TestDeleteShareRewritesPolicy(t *testing.T) {
+	cli := fakeClient(t, "shared")
+	ctx := context.Background()
+
+	policy := GenerateBucketPolicy("shared", "alice", map[string]Perm{"bob": PermRO, "carol": PermRO})
+	if err := cli.PutBucketPolicy(ctx, "shared", policy); err != nil {
+		t.Fatalf("PutBucketPolicy: %v", err)
+	}
+
+	if err := cli.DeleteShare(ctx, "alice", "shared", "bob"); err != nil {
+		t.Fatalf("DeleteShare: %v", err)
+	}
+	shares, err := cli.listBucketShares(ctx, "shared")
+	if err != nil {
+		t.Fatalf("listBucketShares: %v", err)
+	}
+	if users := shareUsers(shares); len(users) != 1 || users[0] != "carol" {
+		t.Fatalf("shares after delete = %v, want [carol]", users)
+	}
+
+	// Revoking a user who has no share, or on a bucket without a policy,
+	// is a no-op rather than an error.
+	if err := cli.DeleteShare(ctx, "alice", "shared", "nobody"); err != nil {
+		t.Fatalf("DeleteShare of non-shared user: %v", err)
+	}
+}
@@ -0,0 +1,716 @@
+// Package s3test provides an in-memory S3 backend implementing the
+// subset of the service the s3 package uses, so client and mount logic
+// can be unit-tested without a network or a real endpoint.
+package s3test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// object is one stored object (or object version).
+type object struct {
+	data      []byte
+	metadata  map[string]string
+	etag      string
+	modTime   time.Time
+	versionID string
+	// archived marks the object's data as offline, the way GLACIER
+	// storage classes behave; see Archive and RestoreObject.
+	archived bool
+}
+
+// bucket is one stored bucket with its objects and policy.
+type bucket struct {
+	created time.Time
+	objects map[string]*object
+	// versions holds each key's history, oldest first, while versioning
+	// is enabled. Deletes drop the current object but keep the history;
+	// delete markers are not modelled.
+	versions   map[string][]*object
+	policy     string
+	versioning bool
+	lifecycle  []types.LifecycleRule
+	cors       []types.CORSRule
+	tags       []types.Tag
+}
+
+// multipart is one in-flight multipart upload.
+type multipart struct {
+	bucket    string
+	key       string
+	initiated time.Time
+	parts     map[int32][]byte
+}
+
+// Backend is an in-memory S3 store. It satisfies the s3 package's S3API
+// interface and is safe for concurrent use. Semantics follow real S3
+// closely enough for the client's purposes: conditional puts return
+// PreconditionFailed, missing keys NotFound/NoSuchKey, ranged gets honor
+// the Range header so the transfer managers work.
+type Backend struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	multiparts  map[string]*multipart
+	nextUpload  int
+	nextVersion int
+}
+
+// New returns an empty backend.
+func New() *Backend {
+	return &Backend{
+		buckets:    make(map[string]*bucket),
+		multiparts: make(map[string]*multipart),
+	}
+}
+
+// apiError mimics a service error with the given code.
+func apiError(code, format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// store makes obj the current object at key, assigning a version ID and
+// recording history when the bucket is versioned. Callers hold b.mu.
+func (b *Backend) store(bkt *bucket, key string, obj *object) {
+	b.nextVersion++
+	obj.versionID = strconv.Itoa(b.nextVersion)
+	if bkt.versioning {
+		bkt.versions[key] = append(bkt.versions[key], obj)
+	}
+	bkt.objects[key] = obj
+}
+
+// getBucket returns the named bucket or a NoSuchBucket error. Callers
+// hold b.mu.
+func (b *Backend) getBucket(name string) (*bucket, error) {
+	bkt, ok := b.buckets[name]
+	if !ok {
+		return nil, apiError("NoSuchBucket", "bucket %s does not exist", name)
+	}
+	return bkt, nil
+}
+
+func (b *Backend) PutObject(ctx context.Context, in *s3v2.PutObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	if want := aws.ToString(in.ContentMD5); want != "" {
+		sum := md5.Sum(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != want {
+			return nil, apiError("BadDigest", "the Content-MD5 you specified did not match what we received")
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	key := aws.ToString(in.Key)
+	if aws.ToString(in.IfNoneMatch) == "*" {
+		if _, exists := bkt.objects[key]; exists {
+			return nil, apiError("PreconditionFailed", "at least one of the preconditions you specified did not hold")
+		}
+	}
+	md := make(map[string]string, len(in.Metadata))
+	for k, v := range in.Metadata {
+		// Real S3 lowercases metadata keys on the way in; mirror that so
+		// tests catch callers relying on case surviving.
+		md[strings.ToLower(k)] = v
+	}
+	obj := &object{data: data, metadata: md, etag: etagOf(data), modTime: time.Now()}
+	b.store(bkt, key, obj)
+	return &s3v2.PutObjectOutput{ETag: aws.String(obj.etag)}, nil
+}
+
+// parseRange interprets a "bytes=start-end" header against size,
+// returning the inclusive range. An empty header selects everything.
+func parseRange(rng string, size int64) (start, end int64, ok bool) {
+	if rng == "" {
+		return 0, size - 1, true
+	}
+	spec, found := strings.CutPrefix(rng, "bytes=")
+	if !found {
+		return 0, 0, false
+	}
+	from, to, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end = size - 1
+	if to != "" {
+		end, err = strconv.ParseInt(to, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, start <= end
+}
+
+func (b *Backend) GetObject(ctx context.Context, in *s3v2.GetObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.GetObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	key := aws.ToString(in.Key)
+	obj, ok := bkt.objects[key]
+	if vid := aws.ToString(in.VersionId); vid != "" {
+		obj, ok = nil, false
+		for _, v := range bkt.versions[key] {
+			if v.versionID == vid {
+				obj, ok = v, true
+				break
+			}
+		}
+		if !ok {
+			return nil, apiError("NoSuchVersion", "the specified version does not exist")
+		}
+	}
+	if !ok {
+		return nil, apiError("NoSuchKey", "the specified key does not exist")
+	}
+	if obj.archived {
+		return nil, apiError("InvalidObjectState", "the operation is not valid for the object's storage class")
+	}
+	if aws.ToString(in.IfNoneMatch) == obj.etag && obj.etag != "" {
+		return nil, apiError("NotModified", "not modified")
+	}
+	size := int64(len(obj.data))
+	rng := aws.ToString(in.Range)
+	if size == 0 {
+		// Any range against an empty object is unsatisfiable on real S3;
+		// returning the empty body keeps the transfer manager happy.
+		rng = ""
+	}
+	start, end, ok := parseRange(rng, size)
+	if !ok {
+		return nil, apiError("InvalidRange", "the requested range is not satisfiable")
+	}
+	body := obj.data[start : end+1]
+	out := &s3v2.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: aws.Int64(int64(len(body))),
+		ETag:          aws.String(obj.etag),
+		LastModified:  aws.Time(obj.modTime),
+		Metadata:      obj.metadata,
+	}
+	if aws.ToString(in.Range) != "" {
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+	return out, nil
+}
+
+func (b *Backend) HeadObject(ctx context.Context, in *s3v2.HeadObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.HeadObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := bkt.objects[aws.ToString(in.Key)]
+	if !ok {
+		// Real S3 reports a bare 404 on HEAD since the body can't carry
+		// the error document.
+		return nil, apiError("NotFound", "not found")
+	}
+	return &s3v2.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(obj.data))),
+		ETag:          aws.String(obj.etag),
+		LastModified:  aws.Time(obj.modTime),
+		Metadata:      obj.metadata,
+	}, nil
+}
+
+// Archive marks bucket/key as offline, so tests can exercise the
+// InvalidObjectState path without a real Glacier transition.
+func (b *Backend) Archive(bucket, key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if bkt, ok := b.buckets[bucket]; ok {
+		if obj, ok := bkt.objects[key]; ok {
+			obj.archived = true
+		}
+	}
+}
+
+func (b *Backend) RestoreObject(ctx context.Context, in *s3v2.RestoreObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.RestoreObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := bkt.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, apiError("NoSuchKey", "the specified key does not exist")
+	}
+	// The fake restores instantly; real restores take minutes to hours.
+	obj.archived = false
+	return &s3v2.RestoreObjectOutput{}, nil
+}
+
+func (b *Backend) CopyObject(ctx context.Context, in *s3v2.CopyObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.CopyObjectOutput, error) {
+	src, err := url.PathUnescape(aws.ToString(in.CopySource))
+	if err != nil {
+		return nil, apiError("InvalidArgument", "bad copy source: %v", err)
+	}
+	srcBucket, srcKey, ok := strings.Cut(strings.TrimPrefix(src, "/"), "/")
+	if !ok {
+		return nil, apiError("InvalidArgument", "copy source %q has no key", src)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sb, err := b.getBucket(srcBucket)
+	if err != nil {
+		return nil, err
+	}
+	srcObj, okSrc := sb.objects[srcKey]
+	if !okSrc {
+		return nil, apiError("NoSuchKey", "the specified key does not exist")
+	}
+	db, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	md := srcObj.metadata
+	if in.MetadataDirective == types.MetadataDirectiveReplace {
+		md = make(map[string]string, len(in.Metadata))
+		for k, v := range in.Metadata {
+			md[strings.ToLower(k)] = v
+		}
+	}
+	dst := &object{data: srcObj.data, metadata: md, etag: srcObj.etag, modTime: time.Now()}
+	b.store(db, aws.ToString(in.Key), dst)
+	return &s3v2.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(dst.etag)},
+	}, nil
+}
+
+func (b *Backend) DeleteObject(ctx context.Context, in *s3v2.DeleteObjectInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteObjectOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	// Deleting a missing key succeeds, as on real S3.
+	delete(bkt.objects, aws.ToString(in.Key))
+	return &s3v2.DeleteObjectOutput{}, nil
+}
+
+func (b *Backend) DeleteObjects(ctx context.Context, in *s3v2.DeleteObjectsInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteObjectsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range in.Delete.Objects {
+		delete(bkt.objects, aws.ToString(id.Key))
+	}
+	return &s3v2.DeleteObjectsOutput{}, nil
+}
+
+func (b *Backend) ListObjectsV2(ctx context.Context, in *s3v2.ListObjectsV2Input, optFns ...func(*s3v2.Options)) (*s3v2.ListObjectsV2Output, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	prefix := aws.ToString(in.Prefix)
+	delimiter := aws.ToString(in.Delimiter)
+	keys := make([]string, 0, len(bkt.objects))
+	for key := range bkt.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	out := &s3v2.ListObjectsV2Output{IsTruncated: aws.Bool(false)}
+	seenPrefix := make(map[string]bool)
+	for _, key := range keys {
+		if delimiter != "" {
+			rest := key[len(prefix):]
+			if i := strings.Index(rest, delimiter); i >= 0 {
+				cp := prefix + rest[:i+len(delimiter)]
+				if !seenPrefix[cp] {
+					seenPrefix[cp] = true
+					out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(cp)})
+				}
+				continue
+			}
+		}
+		obj := bkt.objects[key]
+		out.Contents = append(out.Contents, types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(int64(len(obj.data))),
+			ETag:         aws.String(obj.etag),
+			LastModified: aws.Time(obj.modTime),
+		})
+	}
+	out.KeyCount = aws.Int32(int32(len(out.Contents) + len(out.CommonPrefixes)))
+	return out, nil
+}
+
+func (b *Backend) ListBuckets(ctx context.Context, in *s3v2.ListBucketsInput, optFns ...func(*s3v2.Options)) (*s3v2.ListBucketsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.buckets))
+	for name := range b.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := &s3v2.ListBucketsOutput{}
+	for _, name := range names {
+		out.Buckets = append(out.Buckets, types.Bucket{
+			Name:         aws.String(name),
+			CreationDate: aws.Time(b.buckets[name].created),
+		})
+	}
+	return out, nil
+}
+
+func (b *Backend) CreateBucket(ctx context.Context, in *s3v2.CreateBucketInput, optFns ...func(*s3v2.Options)) (*s3v2.CreateBucketOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	name := aws.ToString(in.Bucket)
+	if _, exists := b.buckets[name]; exists {
+		return nil, apiError("BucketAlreadyOwnedByYou", "bucket %s already exists", name)
+	}
+	b.buckets[name] = &bucket{
+		created:  time.Now(),
+		objects:  make(map[string]*object),
+		versions: make(map[string][]*object),
+	}
+	return &s3v2.CreateBucketOutput{}, nil
+}
+
+func (b *Backend) DeleteBucket(ctx context.Context, in *s3v2.DeleteBucketInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteBucketOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	name := aws.ToString(in.Bucket)
+	bkt, err := b.getBucket(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(bkt.objects) > 0 {
+		return nil, apiError("BucketNotEmpty", "bucket %s is not empty", name)
+	}
+	delete(b.buckets, name)
+	return &s3v2.DeleteBucketOutput{}, nil
+}
+
+func (b *Backend) HeadBucket(ctx context.Context, in *s3v2.HeadBucketInput, optFns ...func(*s3v2.Options)) (*s3v2.HeadBucketOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.getBucket(aws.ToString(in.Bucket)); err != nil {
+		return nil, apiError("NotFound", "not found")
+	}
+	return &s3v2.HeadBucketOutput{}, nil
+}
+
+func (b *Backend) PutBucketPolicy(ctx context.Context, in *s3v2.PutBucketPolicyInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketPolicyOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	bkt.policy = aws.ToString(in.Policy)
+	return &s3v2.PutBucketPolicyOutput{}, nil
+}
+
+func (b *Backend) ListObjectVersions(ctx context.Context, in *s3v2.ListObjectVersionsInput, optFns ...func(*s3v2.Options)) (*s3v2.ListObjectVersionsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	prefix := aws.ToString(in.Prefix)
+	keys := make([]string, 0, len(bkt.versions))
+	for key := range bkt.versions {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	out := &s3v2.ListObjectVersionsOutput{IsTruncated: aws.Bool(false)}
+	for _, key := range keys {
+		history := bkt.versions[key]
+		current := bkt.objects[key]
+		for i := len(history) - 1; i >= 0; i-- {
+			v := history[i]
+			out.Versions = append(out.Versions, types.ObjectVersion{
+				Key:          aws.String(key),
+				VersionId:    aws.String(v.versionID),
+				Size:         aws.Int64(int64(len(v.data))),
+				ETag:         aws.String(v.etag),
+				LastModified: aws.Time(v.modTime),
+				IsLatest:     aws.Bool(v == current),
+			})
+		}
+	}
+	return out, nil
+}
+
+func (b *Backend) PutBucketVersioning(ctx context.Context, in *s3v2.PutBucketVersioningInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketVersioningOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	bkt.versioning = in.VersioningConfiguration != nil &&
+		in.VersioningConfiguration.Status == types.BucketVersioningStatusEnabled
+	return &s3v2.PutBucketVersioningOutput{}, nil
+}
+
+func (b *Backend) GetBucketVersioning(ctx context.Context, in *s3v2.GetBucketVersioningInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketVersioningOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	out := &s3v2.GetBucketVersioningOutput{}
+	if bkt.versioning {
+		out.Status = types.BucketVersioningStatusEnabled
+	} else {
+		out.Status = types.BucketVersioningStatusSuspended
+	}
+	return out, nil
+}
+
+func (b *Backend) PutBucketCors(ctx context.Context, in *s3v2.PutBucketCorsInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketCorsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	bkt.cors = in.CORSConfiguration.CORSRules
+	return &s3v2.PutBucketCorsOutput{}, nil
+}
+
+func (b *Backend) GetBucketCors(ctx context.Context, in *s3v2.GetBucketCorsInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketCorsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	if len(bkt.cors) == 0 {
+		return nil, apiError("NoSuchCORSConfiguration", "the CORS configuration does not exist")
+	}
+	return &s3v2.GetBucketCorsOutput{CORSRules: bkt.cors}, nil
+}
+
+func (b *Backend) PutBucketTagging(ctx context.Context, in *s3v2.PutBucketTaggingInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketTaggingOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	bkt.tags = in.Tagging.TagSet
+	return &s3v2.PutBucketTaggingOutput{}, nil
+}
+
+func (b *Backend) GetBucketTagging(ctx context.Context, in *s3v2.GetBucketTaggingInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketTaggingOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	if len(bkt.tags) == 0 {
+		return nil, apiError("NoSuchTagSet", "the TagSet does not exist")
+	}
+	return &s3v2.GetBucketTaggingOutput{TagSet: bkt.tags}, nil
+}
+
+func (b *Backend) PutBucketLifecycleConfiguration(ctx context.Context, in *s3v2.PutBucketLifecycleConfigurationInput, optFns ...func(*s3v2.Options)) (*s3v2.PutBucketLifecycleConfigurationOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	bkt.lifecycle = in.LifecycleConfiguration.Rules
+	return &s3v2.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func (b *Backend) GetBucketLifecycleConfiguration(ctx context.Context, in *s3v2.GetBucketLifecycleConfigurationInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketLifecycleConfigurationOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	if len(bkt.lifecycle) == 0 {
+		return nil, apiError("NoSuchLifecycleConfiguration", "the lifecycle configuration does not exist")
+	}
+	return &s3v2.GetBucketLifecycleConfigurationOutput{Rules: bkt.lifecycle}, nil
+}
+
+func (b *Backend) GetBucketPolicy(ctx context.Context, in *s3v2.GetBucketPolicyInput, optFns ...func(*s3v2.Options)) (*s3v2.GetBucketPolicyOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	if bkt.policy == "" {
+		return nil, apiError("NoSuchBucketPolicy", "the bucket policy does not exist")
+	}
+	return &s3v2.GetBucketPolicyOutput{Policy: aws.String(bkt.policy)}, nil
+}
+
+func (b *Backend) DeleteBucketPolicy(ctx context.Context, in *s3v2.DeleteBucketPolicyInput, optFns ...func(*s3v2.Options)) (*s3v2.DeleteBucketPolicyOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.getBucket(aws.ToString(in.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	bkt.policy = ""
+	return &s3v2.DeleteBucketPolicyOutput{}, nil
+}
+
+// Policy returns the raw policy document applied to bucket, if any.
+func (b *Backend) Policy(bucketName string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if bkt, ok := b.buckets[bucketName]; ok {
+		return bkt.policy
+	}
+	return ""
+}
+
+func (b *Backend) CreateMultipartUpload(ctx context.Context, in *s3v2.CreateMultipartUploadInput, optFns ...func(*s3v2.Options)) (*s3v2.CreateMultipartUploadOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.getBucket(aws.ToString(in.Bucket)); err != nil {
+		return nil, err
+	}
+	b.nextUpload++
+	id := strconv.Itoa(b.nextUpload)
+	b.multiparts[id] = &multipart{
+		bucket:    aws.ToString(in.Bucket),
+		key:       aws.ToString(in.Key),
+		initiated: time.Now(),
+		parts:     make(map[int32][]byte),
+	}
+	return &s3v2.CreateMultipartUploadOutput{UploadId: aws.String(id)}, nil
+}
+
+func (b *Backend) UploadPart(ctx context.Context, in *s3v2.UploadPartInput, optFns ...func(*s3v2.Options)) (*s3v2.UploadPartOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	up, ok := b.multiparts[aws.ToString(in.UploadId)]
+	if !ok {
+		return nil, apiError("NoSuchUpload", "the specified upload does not exist")
+	}
+	up.parts[aws.ToInt32(in.PartNumber)] = data
+	return &s3v2.UploadPartOutput{ETag: aws.String(etagOf(data))}, nil
+}
+
+func (b *Backend) CompleteMultipartUpload(ctx context.Context, in *s3v2.CompleteMultipartUploadInput, optFns ...func(*s3v2.Options)) (*s3v2.CompleteMultipartUploadOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.ToString(in.UploadId)
+	up, ok := b.multiparts[id]
+	if !ok {
+		return nil, apiError("NoSuchUpload", "the specified upload does not exist")
+	}
+	bkt, err := b.getBucket(up.bucket)
+	if err != nil {
+		return nil, err
+	}
+	numbers := make([]int, 0, len(up.parts))
+	for n := range up.parts {
+		numbers = append(numbers, int(n))
+	}
+	sort.Ints(numbers)
+	var data []byte
+	for _, n := range numbers {
+		data = append(data, up.parts[int32(n)]...)
+	}
+	obj := &object{data: data, metadata: map[string]string{}, etag: etagOf(data), modTime: time.Now()}
+	b.store(bkt, up.key, obj)
+	delete(b.multiparts, id)
+	return &s3v2.CompleteMultipartUploadOutput{ETag: aws.String(obj.etag)}, nil
+}
+
+func (b *Backend) AbortMultipartUpload(ctx context.Context, in *s3v2.AbortMultipartUploadInput, optFns ...func(*s3v2.Options)) (*s3v2.AbortMultipartUploadOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.ToString(in.UploadId)
+	if _, ok := b.multiparts[id]; !ok {
+		return nil, apiError("NoSuchUpload", "the specified upload does not exist")
+	}
+	delete(b.multiparts, id)
+	return &s3v2.AbortMultipartUploadOutput{}, nil
+}
+
+func (b *Backend) ListMultipartUploads(ctx context.Context, in *s3v2.ListMultipartUploadsInput, optFns ...func(*s3v2.Options)) (*s3v2.ListMultipartUploadsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bucketName := aws.ToString(in.Bucket)
+	if _, err := b.getBucket(bucketName); err != nil {
+		return nil, err
+	}
+	prefix := aws.ToString(in.Prefix)
+	out := &s3v2.ListMultipartUploadsOutput{IsTruncated: aws.Bool(false)}
+	for id, up := range b.multiparts {
+		if up.bucket != bucketName || !strings.HasPrefix(up.key, prefix) {
+			continue
+		}
+		out.Uploads = append(out.Uploads, types.MultipartUpload{
+			Key:       aws.String(up.key),
+			UploadId:  aws.String(id),
+			Initiated: aws.Time(up.initiated),
+		})
+	}
+	sort.Slice(out.Uploads, func(i, j int) bool {
+		return aws.ToString(out.Uploads[i].Key) < aws.ToString(out.Uploads[j].Key)
+	})
+	return out, nil
+}
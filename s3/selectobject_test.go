@@ -0,0 +1,35 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSelectSerializationFormats(t *testing.T) {
+	for _, format := range []string{"csv", "CSV", "json", "parquet"} {
+		if _, err := selectInput(format); err != nil {
+			t.Errorf("selectInput(%q): %v", format, err)
+		}
+	}
+	if _, err := selectInput("xml"); err == nil {
+		t.Error("selectInput(xml) succeeded, want error")
+	}
+	for _, format := range []string{"csv", "json"} {
+		if _, err := selectOutput(format); err != nil {
+			t.Errorf("selectOutput(%q): %v", format, err)
+		}
+	}
+	// Parquet is input-only in S3 Select.
+	if _, err := selectOutput("parquet"); err == nil {
+		t.Error("selectOutput(parquet) succeeded, want error")
+	}
+}
+
+func TestSelectObjectNeedsRealBackend(t *testing.T) {
+	cli := fakeClient(t, "select")
+	_, err := cli.SelectObject(context.Background(), "select", "data.csv", "SELECT * FROM S3Object", "csv", "csv")
+	if err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Fatalf("SelectObject on fake backend = %v, want unsupported error", err)
+	}
+}
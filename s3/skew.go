@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// Self-hosted gateways reject requests whose signature timestamp drifts
+// too far from their own clock with RequestTimeTooSkewed — a 403, which
+// plain backoff can never fix because every retry carries the same bad
+// clock. The pieces here turn that failure into a one-time resync: the
+// retry classifier spots the code, reads the server's Date header off
+// the rejected response and stores the difference, and the signer adds
+// the stored offset to the signing time of every request from then on.
+
+// clockSkew tracks the observed offset between the server's clock and
+// ours. The zero value means no skew has been seen.
+type clockSkew struct {
+	mu     sync.Mutex
+	offset time.Duration
+	warned bool
+}
+
+// Offset returns the current signing-time correction.
+func (s *clockSkew) Offset() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset
+}
+
+// observe records the offset derived from the server's Date header,
+// warning on the first detection only; after that the correction is
+// routine and stays quiet.
+func (s *clockSkew) observe(serverDate time.Time, log Logger) {
+	offset := time.Until(serverDate).Round(time.Second)
+	s.mu.Lock()
+	first := !s.warned
+	s.warned = true
+	s.offset = offset
+	s.mu.Unlock()
+	if first {
+		log.Warnf("clock skew detected: local clock is %v off the server's; signing with corrected time", -offset)
+	}
+}
+
+// skewRetry implements retry.IsErrorRetryable for RequestTimeTooSkewed:
+// it resyncs the clock from the response and declares the error
+// retryable, so the very next attempt goes out with a valid signature.
+// It must run before noRetry4xx, which would otherwise make the 403
+// permanent. Every other error is left to the rest of the chain.
+type skewRetry struct {
+	skew *clockSkew
+	log  Logger
+}
+
+func (r skewRetry) IsErrorRetryable(err error) aws.Ternary {
+	if apiErrorCode(err) != "RequestTimeTooSkewed" {
+		return aws.UnknownTernary
+	}
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) && re.Response != nil {
+		if date, derr := http.ParseTime(re.Response.Header.Get("Date")); derr == nil {
+			r.skew.observe(date, r.log)
+			return aws.TrueTernary
+		}
+	}
+	// No usable Date header: nothing to resync against, so don't loop.
+	return aws.FalseTernary
+}
+
+// skewSigner implements s3.HTTPSignerV4 by shifting the signing time by
+// the observed skew before delegating to the standard SigV4 signer.
+type skewSigner struct {
+	signer *v4.Signer
+	skew   *clockSkew
+}
+
+func newSkewSigner(skew *clockSkew) *skewSigner {
+	return &skewSigner{signer: v4.NewSigner(), skew: skew}
+}
+
+func (s *skewSigner) SignHTTP(ctx context.Context, credentials aws.Credentials, r *http.Request, payloadHash string, service string, region string, signingTime time.Time, optFns ...func(*v4.SignerOptions)) error {
+	return s.signer.SignHTTP(ctx, credentials, r, payloadHash, service, region, signingTime.Add(s.skew.Offset()), optFns...)
+}
@@ -0,0 +1,60 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// recordingLogger counts warnings so the warn-once contract is testable.
+type recordingLogger struct {
+	fsLogger
+	warns int
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) { l.warns++ }
+
+func skewedError(serverDate time.Time) error {
+	resp := &http.Response{StatusCode: 403, Header: http.Header{}}
+	resp.Header.Set("Date", serverDate.UTC().Format(http.TimeFormat))
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: resp},
+			Err:      &smithy.GenericAPIError{Code: "RequestTimeTooSkewed", Message: "clock drift"},
+		},
+	}
+}
+
+func TestSkewRetryResyncsAndWarnsOnce(t *testing.T) {
+	skew := &clockSkew{}
+	log := &recordingLogger{}
+	r := skewRetry{skew: skew, log: log}
+
+	serverDate := time.Now().Add(10 * time.Minute)
+	if got := r.IsErrorRetryable(skewedError(serverDate)); got != aws.TrueTernary {
+		t.Fatalf("IsErrorRetryable = %v, want TrueTernary", got)
+	}
+	off := skew.Offset()
+	if off < 9*time.Minute || off > 11*time.Minute {
+		t.Fatalf("Offset = %v, want about 10m", off)
+	}
+	// A second detection updates the offset but stays quiet.
+	r.IsErrorRetryable(skewedError(time.Now().Add(-5 * time.Minute)))
+	if off := skew.Offset(); off > -4*time.Minute {
+		t.Fatalf("Offset after resync = %v, want about -5m", off)
+	}
+	if log.warns != 1 {
+		t.Fatalf("warned %d times, want exactly once", log.warns)
+	}
+
+	// Unrelated errors pass through to the rest of the chain.
+	if got := r.IsErrorRetryable(fmt.Errorf("connection reset")); got != aws.UnknownTernary {
+		t.Fatalf("IsErrorRetryable(transport error) = %v, want UnknownTernary", got)
+	}
+}
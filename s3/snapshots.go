@@ -0,0 +1,218 @@
+package s3
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// snapshotsDirName is the synthetic directory exposed inside every
+// directory of a mounted bucket. It never appears in Readdir output —
+// only an explicit lookup finds it — so plain ls and recursive tools
+// don't descend into version history by accident. A real object named
+// ".snapshots" is shadowed by the virtual directory.
+const snapshotsDirName = ".snapshots"
+
+// s3SnapshotsDir is the ".snapshots" directory of one prefix. Each child
+// is a version ID that at least one direct-child object has, presented
+// as a directory holding the files frozen at that version.
+type s3SnapshotsDir struct {
+	fs.Inode
+
+	cli    *s3Client
+	bucket string
+	prefix string
+}
+
+var _ = (fs.NodeGetattrer)((*s3SnapshotsDir)(nil))
+var _ = (fs.NodeReaddirer)((*s3SnapshotsDir)(nil))
+var _ = (fs.NodeLookuper)((*s3SnapshotsDir)(nil))
+
+// directVersions lists the versions of objects that live directly in this
+// directory, leaving out anything from deeper prefixes.
+func (sd *s3SnapshotsDir) directVersions(ctx context.Context) ([]ObjectVersion, error) {
+	versions, err := sd.cli.ListObjectVersions(ctx, sd.bucket, sd.prefix)
+	if err != nil {
+		return nil, err
+	}
+	direct := versions[:0]
+	for _, v := range versions {
+		rel := strings.TrimPrefix(v.Key, sd.prefix)
+		if rel == "" || strings.Contains(rel, "/") {
+			continue
+		}
+		direct = append(direct, v)
+	}
+	return direct, nil
+}
+
+func (sd *s3SnapshotsDir) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | sd.cli.opt.dirMode()&^0222
+	out.Uid = sd.cli.opt.ownerUID(0)
+	out.Gid = sd.cli.opt.ownerGID(0)
+	return 0
+}
+
+func (sd *s3SnapshotsDir) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	versions, err := sd.directVersions(ctx)
+	if err != nil {
+		return nil, errnoFromS3(err)
+	}
+	seen := make(map[string]bool)
+	var entries []fuse.DirEntry
+	for _, v := range versions {
+		if seen[v.VersionID] {
+			continue
+		}
+		seen[v.VersionID] = true
+		entries = append(entries, fuse.DirEntry{Name: v.VersionID, Mode: fuse.S_IFDIR})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return fs.NewListDirStream(entries), 0
+}
+
+func (sd *s3SnapshotsDir) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	versions, err := sd.directVersions(ctx)
+	if err != nil {
+		return nil, errnoFromS3(err)
+	}
+	for _, v := range versions {
+		if v.VersionID == name {
+			child := &s3SnapshotVersionDir{cli: sd.cli, bucket: sd.bucket, prefix: sd.prefix, versionID: name}
+			return sd.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+// s3SnapshotVersionDir is ".snapshots/<versionID>": the direct-child
+// objects that have a version with that ID, frozen at that version.
+type s3SnapshotVersionDir struct {
+	fs.Inode
+
+	cli       *s3Client
+	bucket    string
+	prefix    string
+	versionID string
+}
+
+var _ = (fs.NodeGetattrer)((*s3SnapshotVersionDir)(nil))
+var _ = (fs.NodeReaddirer)((*s3SnapshotVersionDir)(nil))
+var _ = (fs.NodeLookuper)((*s3SnapshotVersionDir)(nil))
+
+func (vd *s3SnapshotVersionDir) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | vd.cli.opt.dirMode()&^0222
+	out.Uid = vd.cli.opt.ownerUID(0)
+	out.Gid = vd.cli.opt.ownerGID(0)
+	return 0
+}
+
+// members returns the versions in this snapshot keyed by base name.
+func (vd *s3SnapshotVersionDir) members(ctx context.Context) (map[string]ObjectVersion, error) {
+	parent := &s3SnapshotsDir{cli: vd.cli, bucket: vd.bucket, prefix: vd.prefix}
+	versions, err := parent.directVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]ObjectVersion)
+	for _, v := range versions {
+		if v.VersionID != vd.versionID {
+			continue
+		}
+		files[strings.TrimPrefix(v.Key, vd.prefix)] = v
+	}
+	return files, nil
+}
+
+func (vd *s3SnapshotVersionDir) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	files, err := vd.members(ctx)
+	if err != nil {
+		return nil, errnoFromS3(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(files))
+	for name := range files {
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: fuse.S_IFREG})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return fs.NewListDirStream(entries), 0
+}
+
+func (vd *s3SnapshotVersionDir) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	files, err := vd.members(ctx)
+	if err != nil {
+		return nil, errnoFromS3(err)
+	}
+	v, ok := files[name]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	child := &s3SnapshotFile{
+		cli:       vd.cli,
+		bucket:    vd.bucket,
+		key:       v.Key,
+		versionID: v.VersionID,
+		size:      v.Size,
+		mtime:     v.LastModified,
+	}
+	return vd.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+}
+
+// s3SnapshotFile is a read-only file pinned to one version of an object.
+// Reading it fetches that version; writes are refused regardless of the
+// mount's ReadOnly option, since history is immutable.
+type s3SnapshotFile struct {
+	fs.Inode
+
+	cli       *s3Client
+	bucket    string
+	key       string
+	versionID string
+	size      int64
+	mtime     time.Time
+}
+
+var _ = (fs.NodeGetattrer)((*s3SnapshotFile)(nil))
+var _ = (fs.NodeOpener)((*s3SnapshotFile)(nil))
+var _ = (fs.NodeReader)((*s3SnapshotFile)(nil))
+
+func (sf *s3SnapshotFile) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | sf.cli.opt.fileMode()&^0222
+	out.Size = uint64(sf.size)
+	out.SetTimes(nil, &sf.mtime, nil)
+	out.Uid = sf.cli.opt.ownerUID(0)
+	out.Gid = sf.cli.opt.ownerGID(0)
+	return 0
+}
+
+func (sf *s3SnapshotFile) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	data, err := sf.cli.GetObjectVersion(ctx, sf.bucket, sf.key, sf.versionID)
+	if err != nil {
+		return nil, 0, errnoFromS3(err)
+	}
+	return &s3FileHandle{data: data}, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (sf *s3SnapshotFile) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h, ok := fh.(*s3FileHandle)
+	if !ok {
+		return nil, syscall.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if off >= int64(len(h.data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := off + int64(len(dest))
+	if end > int64(len(h.data)) {
+		end = int64(len(h.data))
+	}
+	return fuse.ReadResultData(h.data[off:end]), 0
+}
@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"context"
+	"syscall"
+	"testing"
+)
+
+func TestSnapshotsExposeOldVersions(t *testing.T) {
+	cli := fakeClient(t, "snap")
+	ctx := context.Background()
+	if err := cli.SetBucketVersioning(ctx, "snap", true); err != nil {
+		t.Fatalf("SetBucketVersioning: %v", err)
+	}
+	for _, content := range []string{"v1", "v2"} {
+		if err := cli.PutObject(ctx, "test", "snap", "f", []byte(content)); err != nil {
+			t.Fatalf("PutObject: %v", err)
+		}
+	}
+
+	sd := &s3SnapshotsDir{cli: cli, bucket: "snap"}
+	versions, err := sd.directVersions(ctx)
+	if err != nil {
+		t.Fatalf("directVersions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("got %d versions, want 2", len(versions))
+	}
+	var oldID string
+	for _, v := range versions {
+		if !v.IsLatest {
+			oldID = v.VersionID
+		}
+	}
+
+	vd := &s3SnapshotVersionDir{cli: cli, bucket: "snap", versionID: oldID}
+	files, err := vd.members(ctx)
+	if err != nil {
+		t.Fatalf("members: %v", err)
+	}
+	v, ok := files["f"]
+	if !ok {
+		t.Fatalf("snapshot %s has files %v, want f", oldID, files)
+	}
+
+	sf := &s3SnapshotFile{cli: cli, bucket: "snap", key: v.Key, versionID: v.VersionID, size: v.Size, mtime: v.LastModified}
+	fh, _, errno := sf.Open(ctx, syscall.O_RDONLY)
+	if errno != 0 {
+		t.Fatalf("Open snapshot: %v", errno)
+	}
+	dest := make([]byte, 16)
+	res, errno := sf.Read(ctx, fh, dest, 0)
+	if errno != 0 {
+		t.Fatalf("Read snapshot: %v", errno)
+	}
+	data, _ := res.Bytes(dest)
+	if string(data) != "v1" {
+		t.Fatalf("snapshot content = %q, want the superseded v1", data)
+	}
+
+	// History is immutable: writes are refused even on a writable mount.
+	if _, _, errno := sf.Open(ctx, syscall.O_WRONLY); errno != syscall.EROFS {
+		t.Fatalf("Open snapshot for write = %v, want EROFS", errno)
+	}
+
+	// The virtual directory stays hidden from listings; only an explicit
+	// lookup of the name reaches it.
+	root := &s3Root{cli: cli, bucket: "snap"}
+	stream, errno := root.Readdir(ctx)
+	if errno != 0 {
+		t.Fatalf("Readdir: %v", errno)
+	}
+	for stream.HasNext() {
+		entry, _ := stream.Next()
+		if entry.Name == snapshotsDirName {
+			t.Fatal(".snapshots leaked into Readdir output")
+		}
+	}
+}
+
+func TestSnapshotVersionsScopeToOneDirectory(t *testing.T) {
+	cli := fakeClient(t, "snap2")
+	ctx := context.Background()
+	if err := cli.SetBucketVersioning(ctx, "snap2", true); err != nil {
+		t.Fatalf("SetBucketVersioning: %v", err)
+	}
+	if err := cli.PutObject(ctx, "test", "snap2", "a", []byte("a")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	if err := cli.PutObject(ctx, "test", "snap2", "sub/b", []byte("b")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	// The root's .snapshots only knows versions of the root's own files;
+	// sub/b belongs to sub/'s own .snapshots.
+	sd := &s3SnapshotsDir{cli: cli, bucket: "snap2"}
+	versions, err := sd.directVersions(ctx)
+	if err != nil {
+		t.Fatalf("directVersions: %v", err)
+	}
+	if len(versions) != 1 || versions[0].Key != "a" {
+		t.Fatalf("root snapshots see %v, want only a", versions)
+	}
+
+	sub := &s3SnapshotsDir{cli: cli, bucket: "snap2", prefix: "sub/"}
+	versions, err = sub.directVersions(ctx)
+	if err != nil {
+		t.Fatalf("directVersions sub/: %v", err)
+	}
+	if len(versions) != 1 || versions[0].Key != "sub/b" {
+		t.Fatalf("sub/ snapshots see %v, want only sub/b", versions)
+	}
+}
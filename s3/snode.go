@@ -0,0 +1,304 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ThierryZhou/go-s3fs/fs"
+	"github.com/ThierryZhou/go-s3fs/lib/asyncreader"
+)
+
+// S3Node wraps the byte stream of one object transfer with accounting,
+// optional read-ahead buffering and bandwidth limiting.
+type S3Node struct {
+	ci   *fs.ConfigInfo
+	name string
+	size int64
+	// tb enforces the bandwidth cap, nil when unlimited.
+	tb *tokenBucket
+
+	mu       sync.Mutex
+	ctx      context.Context
+	in       io.ReadCloser // possibly an *asyncreader.AsyncReader over origIn
+	origIn   io.ReadCloser
+	buffered bool
+	bytes    int64
+	start    time.Time
+	// rate is an exponential moving average of the transfer speed in
+	// bytes per second, lastRead the time of the previous sample.
+	rate     float64
+	lastRead time.Time
+	// hasher, when set by WithHash, digests every byte passing through.
+	hasher hash.Hash
+}
+
+// Stats is a point-in-time snapshot of one transfer's accounting.
+type Stats struct {
+	// Bytes is the number of bytes read so far, Size the expected total
+	// (negative when unknown).
+	Bytes int64
+	Size  int64
+	// Rate is the smoothed transfer rate in bytes per second.
+	Rate float64
+	// Elapsed is the time since the transfer started.
+	Elapsed time.Duration
+}
+
+// newS3Node wraps in, which carries size bytes of object name, in an
+// accounting reader configured from ci.
+func newS3Node(ctx context.Context, in io.ReadCloser, size int64, name string, ci *fs.ConfigInfo) *S3Node {
+	sno := &S3Node{
+		ctx:    ctx,
+		in:     in,
+		origIn: in,
+		size:   size,
+		name:   name,
+		ci:     ci,
+		start:  time.Now(),
+	}
+	if ci.BwLimit > 0 {
+		sno.tb = newTokenBucket(int64(ci.BwLimit))
+	}
+	return sno
+}
+
+// WithBuffer inserts read-ahead buffering, splitting ci.BufferSize into
+// asyncreader buffers. With a BufferSize below one buffer this is a no-op.
+func (sno *S3Node) WithBuffer() *S3Node {
+	buffers := int(int64(sno.ci.BufferSize) / asyncreader.BufferSize)
+	if buffers <= 0 {
+		return sno
+	}
+	sno.mu.Lock()
+	defer sno.mu.Unlock()
+	ar, err := asyncreader.New(sno.origIn, buffers)
+	if err != nil {
+		fs.Warnf("buffer %s error: %v", ShortenName(sno.name, 40), err)
+		return sno
+	}
+	sno.in = ar
+	sno.buffered = true
+	return sno
+}
+
+// checkReadBefore blocks until the bandwidth limiter admits a read of up
+// to n bytes, respecting cancellation of the transfer's context.
+func (sno *S3Node) checkReadBefore(n int) error {
+	// UpdateReader swaps sno.ctx under the lock; take a snapshot so a
+	// read racing the swap waits on one coherent context.
+	sno.mu.Lock()
+	ctx := sno.ctx
+	sno.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	if sno.tb == nil {
+		return nil
+	}
+	return sno.tb.wait(ctx, int64(n))
+}
+
+// rateHalfLife controls how quickly the moving average forgets old
+// samples.
+const rateHalfLife = time.Second
+
+// checkReadAfter records the bytes actually transferred and folds the
+// sample into the moving transfer rate and the optional digest.
+func (sno *S3Node) checkReadAfter(p []byte, n int, err error) {
+	now := time.Now()
+	sno.mu.Lock()
+	sno.bytes += int64(n)
+	if sno.hasher != nil && n > 0 {
+		sno.hasher.Write(p[:n])
+	}
+	prev := sno.lastRead
+	if prev.IsZero() {
+		prev = sno.start
+	}
+	if dt := now.Sub(prev).Seconds(); dt > 0 {
+		sample := float64(n) / dt
+		weight := math.Exp2(-dt / rateHalfLife.Seconds())
+		sno.rate = sno.rate*weight + sample*(1-weight)
+	}
+	sno.lastRead = now
+	sno.mu.Unlock()
+}
+
+// Snapshot returns the transfer's accounting so far.
+func (sno *S3Node) Snapshot() Stats {
+	sno.mu.Lock()
+	defer sno.mu.Unlock()
+	return Stats{
+		Bytes:   sno.bytes,
+		Size:    sno.size,
+		Rate:    sno.rate,
+		Elapsed: time.Since(sno.start),
+	}
+}
+
+func (sno *S3Node) Read(p []byte) (int, error) {
+	if err := sno.checkReadBefore(len(p)); err != nil {
+		return 0, err
+	}
+	sno.mu.Lock()
+	in := sno.in
+	sno.mu.Unlock()
+	n, err := in.Read(p)
+	sno.checkReadAfter(p, n, err)
+	return n, err
+}
+
+// WithHash enables a rolling digest ("md5" or "sha256") over every byte
+// read through the node, so a download can be verified against a known
+// checksum without a second pass over the data.
+func (sno *S3Node) WithHash(name string) error {
+	var h hash.Hash
+	switch strings.ToLower(name) {
+	case "md5":
+		h = md5.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return fmt.Errorf("unsupported hash %q", name)
+	}
+	sno.mu.Lock()
+	sno.hasher = h
+	sno.mu.Unlock()
+	return nil
+}
+
+// Sum returns the digest of everything read so far, or nil when WithHash
+// wasn't enabled. It is only meaningful once the stream is drained.
+func (sno *S3Node) Sum() []byte {
+	sno.mu.Lock()
+	defer sno.mu.Unlock()
+	if sno.hasher == nil {
+		return nil
+	}
+	return sno.hasher.Sum(nil)
+}
+
+// UpdateReader swaps the underlying stream, e.g. after the object was
+// truncated or reopened, abandoning any read-ahead over the old one and
+// re-buffering over the new one if buffering was on. The whole swap
+// happens under sno.mu — the same lock Read and StopBuffering take — so a
+// read racing the swap sees either the old or the new reader, never a
+// half-replaced pair.
+func (sno *S3Node) UpdateReader(ctx context.Context, in io.ReadCloser) {
+	sno.mu.Lock()
+	if ar, ok := sno.in.(*asyncreader.AsyncReader); ok {
+		ar.Abandon()
+	} else {
+		sno.in.Close()
+	}
+	sno.ctx = ctx
+	sno.in = in
+	sno.origIn = in
+	rebuffer := sno.buffered
+	sno.buffered = false
+	sno.mu.Unlock()
+	if rebuffer {
+		sno.WithBuffer()
+	}
+}
+
+// StopBuffering halts read-ahead while keeping buffered data readable.
+func (sno *S3Node) StopBuffering() {
+	sno.mu.Lock()
+	defer sno.mu.Unlock()
+	if ar, ok := sno.in.(*asyncreader.AsyncReader); ok {
+		ar.StopBuffering()
+	}
+}
+
+// Close shuts the stream down.
+func (sno *S3Node) Close() error {
+	sno.mu.Lock()
+	defer sno.mu.Unlock()
+	return sno.in.Close()
+}
+
+// tokenBucket is a minimal token bucket holding at most one second's worth
+// of the rate, refilled continuously.
+type tokenBucket struct {
+	mu    sync.Mutex
+	rate  int64
+	avail float64
+	last  time.Time
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	return &tokenBucket{rate: rate, avail: float64(rate), last: time.Now()}
+}
+
+// wait blocks until n tokens are available or ctx is cancelled. The
+// bucket never holds more than one second's worth of the rate, so a
+// request larger than that is charged in capacity-sized chunks rather
+// than waiting forever for tokens that can't accumulate.
+func (tb *tokenBucket) wait(ctx context.Context, n int64) error {
+	for n > 0 {
+		chunk := n
+		if chunk > tb.rate {
+			chunk = tb.rate
+		}
+		if err := tb.waitChunk(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// waitChunk blocks for n tokens, which must not exceed the capacity.
+func (tb *tokenBucket) waitChunk(ctx context.Context, n int64) error {
+	for {
+		tb.mu.Lock()
+		now := time.Now()
+		tb.avail += now.Sub(tb.last).Seconds() * float64(tb.rate)
+		if max := float64(tb.rate); tb.avail > max {
+			tb.avail = max
+		}
+		tb.last = now
+		if tb.avail >= float64(n) {
+			tb.avail -= float64(n)
+			tb.mu.Unlock()
+			return nil
+		}
+		missing := float64(n) - tb.avail
+		tb.mu.Unlock()
+		delay := time.Duration(missing / float64(tb.rate) * float64(time.Second))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// ShortenName cuts in down to size runes, marking the cut in the middle
+// with an ellipsis. It is used for log and progress lines where long
+// object keys would wrap; counting is by rune, so multibyte keys never
+// get split inside a character.
+func ShortenName(in string, size int) string {
+	if size <= 0 || utf8.RuneCountInString(in) <= size {
+		return in
+	}
+	name := []rune(in)
+	size--
+	suffixLength := size / 2
+	prefixLength := size - suffixLength
+	return string(name[:prefixLength]) + "…" + string(name[len(name)-suffixLength:])
+}
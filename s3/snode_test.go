@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ThierryZhou/go-s3fs/fs"
+)
+
+// TestUpdateReaderConcurrentRead exercises reader swaps racing in-flight
+// reads; run with -race to catch locking regressions around Abandon and
+// StopBuffering.
+func TestUpdateReaderConcurrentRead(t *testing.T) {
+	const size = 4 << 20
+	newReader := func() io.ReadCloser {
+		return io.NopCloser(bytes.NewReader(make([]byte, size)))
+	}
+	sno := newS3Node(context.Background(), newReader(), size, "race-test", fs.DefaultConfig()).WithBuffer()
+	defer sno.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 32*1024)
+		for i := 0; i < 2000; i++ {
+			if _, err := sno.Read(buf); err != nil {
+				if err != io.EOF {
+					t.Errorf("Read: %v", err)
+				}
+				return
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		sno.UpdateReader(context.Background(), newReader())
+		sno.StopBuffering()
+	}
+	<-done
+}
+
+// TestTokenBucketAdmitsOversizedReads asks for more tokens than the
+// bucket can ever hold; without capacity-sized chunking the wait spins
+// forever and the context deadline fires.
+func TestTokenBucketAdmitsOversizedReads(t *testing.T) {
+	tb := newTokenBucket(1 << 30) // high rate so the chunked wait is short
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tb.wait(ctx, 3<<29); err != nil {
+		t.Fatalf("wait for one and a half bucket capacities: %v", err)
+	}
+}
+
+func TestShortenName(t *testing.T) {
+	cases := []struct {
+		in   string
+		size int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly-ten", 11, "exactly-ten"},
+		{"abcdefghijklmnop", 9, "abcd…mnop"},
+		{"", 5, ""},
+		{"anything", 0, "anything"},
+		// Multibyte keys must be cut between runes, never inside one.
+		{"数据集/训练/样本一二三四五六", 7, "数据集…四五六"},
+		{"éééééééééé", 5, "éé…éé"},
+	}
+	for _, tc := range cases {
+		got := ShortenName(tc.in, tc.size)
+		if got != tc.want {
+			t.Errorf("ShortenName(%q, %d) = %q, want %q", tc.in, tc.size, got, tc.want)
+		}
+		if tc.size > 0 && utf8.RuneCountInString(got) > tc.size {
+			t.Errorf("ShortenName(%q, %d) = %q: %d runes, want <= %d",
+				tc.in, tc.size, got, utf8.RuneCountInString(got), tc.size)
+		}
+	}
+}
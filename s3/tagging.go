@@ -0,0 +1,60 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PutBucketTagging replaces the bucket's tag set with tags, the way cost
+// allocation wants it: one flat map of key/value pairs. The tags are sent
+// in sorted key order so repeated calls with the same map are
+// byte-identical on the wire.
+func (c *s3Client) PutBucketTagging(ctx context.Context, bucket string, tags map[string]string) error {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sdkTags := make([]types.Tag, len(keys))
+	for i, k := range keys {
+		sdkTags[i] = types.Tag{Key: aws.String(k), Value: aws.String(tags[k])}
+	}
+	_, err := c.cli.PutBucketTagging(ctx, &s3v2.PutBucketTaggingInput{
+		Bucket:  aws.String(bucket),
+		Tagging: &types.Tagging{TagSet: sdkTags},
+	})
+	if err != nil {
+		c.log.Errorf("put bucket tagging %s error: %v", bucket, err)
+		return fmt.Errorf("put bucket tagging %s: %w", bucket, classify(err))
+	}
+	return nil
+}
+
+// GetBucketTagging returns the bucket's tags; a bucket that was never
+// tagged returns an empty map rather than an error.
+func (c *s3Client) GetBucketTagging(ctx context.Context, bucket string) (map[string]string, error) {
+	ctx, cancel := c.opCtx(ctx)
+	defer cancel()
+	out, err := c.cli.GetBucketTagging(ctx, &s3v2.GetBucketTaggingInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if apiErrorCode(err) == "NoSuchTagSet" {
+			return map[string]string{}, nil
+		}
+		c.log.Errorf("get bucket tagging %s error: %v", bucket, err)
+		return nil, fmt.Errorf("get bucket tagging %s: %w", bucket, classify(err))
+	}
+	tags := make(map[string]string, len(out.TagSet))
+	for _, t := range out.TagSet {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return tags, nil
+}
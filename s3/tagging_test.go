@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBucketTaggingRoundTrip(t *testing.T) {
+	cli := fakeClient(t, "tagging")
+	ctx := context.Background()
+
+	// An untagged bucket reports an empty map, not an error.
+	tags, err := cli.GetBucketTagging(ctx, "tagging")
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("GetBucketTagging on fresh bucket = %v, %v; want empty, nil", tags, err)
+	}
+
+	want := map[string]string{"team": "ml-infra", "cost-center": "1234"}
+	if err := cli.PutBucketTagging(ctx, "tagging", want); err != nil {
+		t.Fatalf("PutBucketTagging: %v", err)
+	}
+	got, err := cli.GetBucketTagging(ctx, "tagging")
+	if err != nil {
+		t.Fatalf("GetBucketTagging: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tags round-tripped to %v, want %v", got, want)
+	}
+}
+
+func TestCreateBucketAppliesTags(t *testing.T) {
+	cli := fakeClient(t, "seed")
+	ctx := context.Background()
+	want := map[string]string{"env": "prod"}
+	if _, err := cli.CreateBucket(ctx, "test", "tagged-at-birth", want); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	got, err := cli.GetBucketTagging(ctx, "tagged-at-birth")
+	if err != nil {
+		t.Fatalf("GetBucketTagging: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tags = %v, want %v", got, want)
+	}
+}
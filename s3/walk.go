@@ -0,0 +1,62 @@
+package s3
+
+import (
+	"context"
+	"errors"
+)
+
+// SkipDir can be returned by a Walk callback to skip the directory it
+// was just given (or, when returned for a file, the rest of that file's
+// directory), mirroring filepath.SkipDir. Walk treats it as flow
+// control, never as a failure.
+var SkipDir = errors.New("skip this directory")
+
+// Walk descends the tree under root — a prefix ending in "/" or "" for
+// the whole bucket — calling fn for every directory and object, parents
+// before children, siblings in key order. A listing failure is handed to
+// fn with the error set and the walk continues with the next sibling
+// unless fn returns it (or anything else non-nil) back. fn may return
+// SkipDir as described on it. Each directory costs one delimiter
+// listing, so sync tools can prune whole subtrees they know are clean.
+func (c *s3Client) Walk(ctx context.Context, userID, bucket, root string, fn func(path string, obj Object, err error) error) error {
+	err := fn(root, Object{Key: root, IsDir: true}, nil)
+	if errors.Is(err, SkipDir) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = c.walk(ctx, userID, bucket, root, fn)
+	if errors.Is(err, SkipDir) {
+		return nil
+	}
+	return err
+}
+
+// walk lists one directory level and recurses; the root entry itself has
+// already been visited by the caller.
+func (c *s3Client) walk(ctx context.Context, userID, bucket, prefix string, fn func(path string, obj Object, err error) error) error {
+	objs, err := c.ListObject(ctx, userID, bucket, prefix)
+	if err != nil {
+		return fn(prefix, Object{Key: prefix, IsDir: true}, err)
+	}
+	for _, obj := range objs {
+		err := fn(obj.Key, obj, nil)
+		if errors.Is(err, SkipDir) {
+			if obj.IsDir {
+				continue
+			}
+			// SkipDir on a file abandons the rest of this directory.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if obj.IsDir {
+			if err := c.walk(ctx, userID, bucket, obj.Key, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
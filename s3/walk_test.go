@@ -0,0 +1,71 @@
+package s3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func walkBucket(t *testing.T) *s3Client {
+	t.Helper()
+	cli := fakeClient(t, "walk")
+	ctx := context.Background()
+	for _, k := range []string{"a/1", "a/2", "b/sub/3", "top"} {
+		if err := cli.PutObject(ctx, "test", "walk", k, []byte("x")); err != nil {
+			t.Fatalf("PutObject %s: %v", k, err)
+		}
+	}
+	return cli
+}
+
+func TestWalkVisitsEverythingInOrder(t *testing.T) {
+	cli := walkBucket(t)
+	var visited []string
+	err := cli.Walk(context.Background(), "test", "walk", "", func(path string, obj Object, err error) error {
+		if err != nil {
+			t.Fatalf("callback got error for %q: %v", path, err)
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	want := []string{"", "a/", "a/1", "a/2", "b/", "b/sub/", "b/sub/3", "top"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Fatalf("visited %v, want %v", visited, want)
+	}
+}
+
+func TestWalkSkipDirPrunesSubtree(t *testing.T) {
+	cli := walkBucket(t)
+	var visited []string
+	err := cli.Walk(context.Background(), "test", "walk", "", func(path string, obj Object, err error) error {
+		visited = append(visited, path)
+		if obj.IsDir && path == "b/" {
+			return SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	want := []string{"", "a/", "a/1", "a/2", "b/", "top"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Fatalf("visited %v, want %v", visited, want)
+	}
+}
+
+func TestWalkErrorStopsWhenReturned(t *testing.T) {
+	cli := walkBucket(t)
+	boom := context.DeadlineExceeded
+	err := cli.Walk(context.Background(), "test", "walk", "", func(path string, obj Object, err error) error {
+		if path == "a/2" {
+			return boom
+		}
+		return nil
+	})
+	if err != boom {
+		t.Fatalf("Walk = %v, want the callback's error", err)
+	}
+}
@@ -0,0 +1,181 @@
+package s3
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WriteBuffer is the write-side companion to S3Node: it accepts random
+// WriteAt calls, keeps the object body in memory and tracks which byte
+// ranges were actually touched. Flush walks only the dirty extents, so
+// a small edit in a large object doesn't re-upload untouched regions on
+// backends that accept ranged writes, and an append-only workload
+// degenerates to a single trailing extent that can be streamed. The
+// dirty byte count feeds the progress display.
+type WriteBuffer struct {
+	mu   sync.Mutex
+	data []byte
+	// extents are the dirty ranges, kept sorted and non-overlapping;
+	// adjacent and overlapping writes coalesce on entry.
+	extents []extent
+}
+
+// extent is a half-open dirty range [start, end).
+type extent struct {
+	start, end int64
+}
+
+// NewWriteBuffer returns a buffer primed with the object's current
+// content, which may be nil for a new object. The initial content is
+// clean: only subsequent writes mark extents dirty.
+func NewWriteBuffer(content []byte) *WriteBuffer {
+	return &WriteBuffer{data: content}
+}
+
+// WriteAt copies p into the buffer at off, growing it as needed, and
+// records the range as dirty. It never fails; the error is there to
+// satisfy io.WriterAt.
+func (wb *WriteBuffer) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("write at negative offset %d", off)
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	end := off + int64(len(p))
+	if end > int64(len(wb.data)) {
+		grown := make([]byte, end)
+		copy(grown, wb.data)
+		wb.data = grown
+	}
+	copy(wb.data[off:end], p)
+	wb.markDirty(off, end)
+	return len(p), nil
+}
+
+// markDirty merges [start, end) into the extent list. Caller holds mu.
+func (wb *WriteBuffer) markDirty(start, end int64) {
+	if start >= end {
+		return
+	}
+	merged := make([]extent, 0, len(wb.extents)+1)
+	for _, e := range wb.extents {
+		switch {
+		case e.end < start:
+			// Entirely before the new range.
+			merged = append(merged, e)
+		case e.start > end:
+			// Entirely after: the new range is complete, flush it first.
+			if start < end {
+				merged = append(merged, extent{start, end})
+				start, end = 0, 0
+			}
+			merged = append(merged, e)
+		default:
+			// Touching or overlapping: absorb into the new range.
+			if e.start < start {
+				start = e.start
+			}
+			if e.end > end {
+				end = e.end
+			}
+		}
+	}
+	if start < end {
+		merged = append(merged, extent{start, end})
+	}
+	wb.extents = merged
+}
+
+// Truncate cuts or zero-extends the buffer to size. Shrinking clips
+// dirty extents; growing marks the new tail dirty so the extension is
+// uploaded.
+func (wb *WriteBuffer) Truncate(size int64) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	old := int64(len(wb.data))
+	switch {
+	case size < old:
+		wb.data = wb.data[:size]
+		kept := wb.extents[:0]
+		for _, e := range wb.extents {
+			if e.start >= size {
+				continue
+			}
+			if e.end > size {
+				e.end = size
+			}
+			kept = append(kept, e)
+		}
+		wb.extents = kept
+	case size > old:
+		grown := make([]byte, size)
+		copy(grown, wb.data)
+		wb.data = grown
+		wb.markDirty(old, size)
+	}
+}
+
+// Len returns the current buffer length.
+func (wb *WriteBuffer) Len() int64 {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return int64(len(wb.data))
+}
+
+// DirtyBytes returns how many bytes changed since the last flush, for
+// progress reporting.
+func (wb *WriteBuffer) DirtyBytes() int64 {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	var n int64
+	for _, e := range wb.extents {
+		n += e.end - e.start
+	}
+	return n
+}
+
+// Dirty reports whether any write happened since the last flush.
+func (wb *WriteBuffer) Dirty() bool {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return len(wb.extents) > 0
+}
+
+// Bytes returns the full current content. The slice aliases the
+// internal buffer; callers must not retain it across further writes.
+func (wb *WriteBuffer) Bytes() []byte {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return wb.data
+}
+
+// Flush calls fn once per dirty extent, in ascending offset order. The
+// extent list is swapped out under the lock before fn runs, so a
+// WriteAt landing mid-flush records its extent in the fresh list and
+// stays dirty for the next flush instead of being wiped by this one.
+// On error the unflushed extents are merged back so the flush can be
+// retried.
+func (wb *WriteBuffer) Flush(fn func(off int64, data []byte) error) error {
+	wb.mu.Lock()
+	extents := wb.extents
+	wb.extents = nil
+	// Copy the dirty bytes while still holding the lock: fn runs
+	// unlocked, and a concurrent WriteAt may grow or rewrite wb.data
+	// under it.
+	chunks := make([][]byte, len(extents))
+	for i, e := range extents {
+		chunks[i] = append([]byte(nil), wb.data[e.start:e.end]...)
+	}
+	wb.mu.Unlock()
+	for i, e := range extents {
+		if err := fn(e.start, chunks[i]); err != nil {
+			wb.mu.Lock()
+			for _, rest := range extents[i:] {
+				wb.markDirty(rest.start, rest.end)
+			}
+			wb.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriteBufferCoalescesExtents(t *testing.T) {
+	wb := NewWriteBuffer(make([]byte, 100))
+	wb.WriteAt([]byte("aaaa"), 0)  // [0,4)
+	wb.WriteAt([]byte("bbbb"), 4)  // adjacent: [0,8)
+	wb.WriteAt([]byte("cccc"), 20) // separate: [20,24)
+	wb.WriteAt([]byte("dd"), 6)    // overlapping: still [0,8)
+
+	if got := wb.DirtyBytes(); got != 12 {
+		t.Fatalf("DirtyBytes = %d, want 12", got)
+	}
+	if len(wb.extents) != 2 {
+		t.Fatalf("extents = %+v, want two ranges", wb.extents)
+	}
+	if wb.extents[0] != (extent{0, 8}) || wb.extents[1] != (extent{20, 24}) {
+		t.Fatalf("extents = %+v, want [0,8) and [20,24)", wb.extents)
+	}
+
+	// A write bridging the gap collapses everything into one extent.
+	wb.WriteAt(make([]byte, 12), 8)
+	if len(wb.extents) != 1 || wb.extents[0] != (extent{0, 24}) {
+		t.Fatalf("extents after bridge = %+v, want [0,24)", wb.extents)
+	}
+}
+
+func TestWriteBufferFlushOnlyDirty(t *testing.T) {
+	wb := NewWriteBuffer([]byte("0123456789"))
+	wb.WriteAt([]byte("AB"), 2)
+	wb.WriteAt([]byte("CD"), 7)
+
+	var offs []int64
+	var parts []string
+	err := wb.Flush(func(off int64, data []byte) error {
+		offs = append(offs, off)
+		parts = append(parts, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(offs) != 2 || offs[0] != 2 || offs[1] != 7 || parts[0] != "AB" || parts[1] != "CD" {
+		t.Fatalf("flushed %v %v, want AB@2 and CD@7", offs, parts)
+	}
+	if wb.Dirty() {
+		t.Fatal("buffer still dirty after successful flush")
+	}
+	if !bytes.Equal(wb.Bytes(), []byte("01AB456CD9")) {
+		t.Fatalf("content = %q", wb.Bytes())
+	}
+}
+
+func TestWriteBufferTruncate(t *testing.T) {
+	wb := NewWriteBuffer([]byte("0123456789"))
+	wb.WriteAt([]byte("XY"), 8)
+	wb.Truncate(5)
+	if wb.Len() != 5 || wb.Dirty() {
+		t.Fatalf("after shrink: len=%d dirty=%v, want 5/clean", wb.Len(), wb.Dirty())
+	}
+	wb.Truncate(8)
+	if wb.Len() != 8 || wb.DirtyBytes() != 3 {
+		t.Fatalf("after grow: len=%d dirty=%d, want 8/3", wb.Len(), wb.DirtyBytes())
+	}
+}
+
+// TestWriteBufferFlushKeepsConcurrentWrites pins the mid-flush race: a
+// write landing while extents are being uploaded must stay dirty after
+// the flush succeeds, and a failed flush must keep its unflushed
+// extents for retry.
+func TestWriteBufferFlushKeepsConcurrentWrites(t *testing.T) {
+	wb := NewWriteBuffer([]byte("0123456789"))
+	wb.WriteAt([]byte("AB"), 2)
+	err := wb.Flush(func(off int64, data []byte) error {
+		// Simulate a write racing the upload; fn runs unlocked.
+		wb.WriteAt([]byte("Z"), 7)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if wb.DirtyBytes() != 1 {
+		t.Fatalf("dirty bytes after flush = %d, want the mid-flush write kept", wb.DirtyBytes())
+	}
+	var got []extent
+	if err := wb.Flush(func(off int64, data []byte) error {
+		got = append(got, extent{off, off + int64(len(data))})
+		return nil
+	}); err != nil {
+		t.Fatalf("second Flush: %v", err)
+	}
+	if len(got) != 1 || got[0] != (extent{7, 8}) {
+		t.Fatalf("second flush uploaded %v, want just [7,8)", got)
+	}
+
+	// A failing flush re-marks what wasn't uploaded.
+	wb.WriteAt([]byte("QR"), 0)
+	boom := errors.New("upload failed")
+	if err := wb.Flush(func(int64, []byte) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("Flush error = %v, want the callback's", err)
+	}
+	if wb.DirtyBytes() != 2 {
+		t.Fatalf("dirty bytes after failed flush = %d, want 2 kept for retry", wb.DirtyBytes())
+	}
+}
@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"context"
+	"sync"
+)
+
+// writeback stages dirty file contents and uploads them asynchronously
+// through a bounded worker pool, so releasing many small files doesn't
+// stall on one synchronous round-trip each. Upload failures are remembered
+// per key and reported by the next Sync.
+type writeback struct {
+	cli  *s3Client
+	jobs chan writebackJob
+	// workers tracks the worker goroutines.
+	workers sync.WaitGroup
+
+	mu sync.Mutex
+	// pending counts queued but unfinished jobs; idle is broadcast when
+	// it drops to zero so Sync can wait for the queue to drain. A plain
+	// counter under mu rather than a WaitGroup: enqueue and Sync run
+	// from independent FUSE goroutines, and a WaitGroup panics when Add
+	// races a blocked Wait across drain cycles.
+	pending int
+	idle    *sync.Cond
+	errs    map[string]error
+	closed  bool
+}
+
+type writebackJob struct {
+	bucket   string
+	key      string
+	data     []byte
+	metadata map[string]string
+}
+
+func newWriteback(cli *s3Client, workers int) *writeback {
+	if workers <= 0 {
+		workers = MAX_GOROUTES
+	}
+	wb := &writeback{
+		cli:  cli,
+		jobs: make(chan writebackJob, workers*2),
+		errs: make(map[string]error),
+	}
+	wb.idle = sync.NewCond(&wb.mu)
+	for i := 0; i < workers; i++ {
+		wb.workers.Add(1)
+		go wb.worker()
+	}
+	return wb
+}
+
+func (wb *writeback) worker() {
+	defer wb.workers.Done()
+	for job := range wb.jobs {
+		// The FUSE request context is gone by the time the upload runs.
+		err := wb.cli.PutObject(context.Background(), wb.cli.opt.UserID,
+			job.bucket, job.key, job.data, WithMetadata(job.metadata))
+		wb.mu.Lock()
+		if err != nil {
+			wb.cli.log.Warnf("write-back of %s/%s failed: %v", job.bucket, job.key, err)
+			wb.errs[job.bucket+"/"+job.key] = err
+		} else {
+			delete(wb.errs, job.bucket+"/"+job.key)
+		}
+		wb.pending--
+		if wb.pending == 0 {
+			wb.idle.Broadcast()
+		}
+		wb.mu.Unlock()
+	}
+}
+
+// enqueue stages one upload and returns immediately. The caller must not
+// reuse data afterwards.
+func (wb *writeback) enqueue(bucket, key string, data []byte, metadata map[string]string) {
+	wb.mu.Lock()
+	wb.pending++
+	wb.mu.Unlock()
+	wb.jobs <- writebackJob{bucket: bucket, key: key, data: data, metadata: metadata}
+}
+
+// Sync blocks until every queued upload has completed, returning one
+// remembered error (if any) and clearing the error state.
+func (wb *writeback) Sync() error {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	for wb.pending > 0 {
+		wb.idle.Wait()
+	}
+	var first error
+	for key, err := range wb.errs {
+		if first == nil {
+			first = err
+		}
+		delete(wb.errs, key)
+	}
+	return first
+}
+
+// close drains the queue and stops the workers. Used on shutdown so no
+// staged data is lost.
+func (wb *writeback) close() error {
+	err := wb.Sync()
+	wb.mu.Lock()
+	if !wb.closed {
+		wb.closed = true
+		close(wb.jobs)
+	}
+	wb.mu.Unlock()
+	wb.workers.Wait()
+	return err
+}
+
+// Sync blocks until all pending write-back uploads are acknowledged. It is
+// a no-op when write-back is disabled.
+func (c *s3Client) Sync() error {
+	if c.wb == nil {
+		return nil
+	}
+	return c.wb.Sync()
+}
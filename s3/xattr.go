@@ -0,0 +1,132 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+)
+
+// Extended attributes expose S3 object metadata under the user.s3.*
+// namespace: user.s3.<key> maps to the x-amz-meta-<key> metadata entry.
+// Two attributes are read-only views of object properties rather than
+// metadata: user.s3.etag and user.s3.storage-class.
+const (
+	xattrPrefix       = "user.s3."
+	xattrETag         = xattrPrefix + "etag"
+	xattrStorageClass = xattrPrefix + "storage-class"
+)
+
+var _ = (fs.NodeGetxattrer)((*s3File)(nil))
+var _ = (fs.NodeSetxattrer)((*s3File)(nil))
+var _ = (fs.NodeListxattrer)((*s3File)(nil))
+var _ = (fs.NodeRemovexattrer)((*s3File)(nil))
+
+// xattrReply copies value into dest following the xattr protocol: a
+// too-small dest gets ERANGE and the needed size, so callers can size a
+// second call.
+func xattrReply(dest []byte, value string) (uint32, syscall.Errno) {
+	if len(dest) < len(value) {
+		return uint32(len(value)), syscall.ERANGE
+	}
+	copy(dest, value)
+	return uint32(len(value)), 0
+}
+
+func (f *s3File) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	if !strings.HasPrefix(attr, xattrPrefix) {
+		return 0, syscall.ENODATA
+	}
+	obj, err := f.cli.HeadObject(ctx, f.cli.opt.UserID, f.bucket, f.key)
+	if err != nil {
+		return 0, errnoFromS3(err)
+	}
+	switch attr {
+	case xattrETag:
+		return xattrReply(dest, obj.ETag)
+	case xattrStorageClass:
+		sc := obj.StorageClass
+		if sc == "" {
+			sc = "STANDARD"
+		}
+		return xattrReply(dest, sc)
+	}
+	value, ok := obj.Metadata[strings.TrimPrefix(attr, xattrPrefix)]
+	if !ok {
+		return 0, syscall.ENODATA
+	}
+	return xattrReply(dest, value)
+}
+
+func (f *s3File) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	if f.cli.opt.ReadOnly {
+		return syscall.EROFS
+	}
+	if !strings.HasPrefix(attr, xattrPrefix) {
+		return syscall.EOPNOTSUPP
+	}
+	if attr == xattrETag || attr == xattrStorageClass {
+		return syscall.EPERM
+	}
+	obj, err := f.cli.HeadObject(ctx, f.cli.opt.UserID, f.bucket, f.key)
+	if err != nil {
+		return errnoFromS3(err)
+	}
+	md := make(map[string]string, len(obj.Metadata)+1)
+	for k, v := range obj.Metadata {
+		md[k] = v
+	}
+	md[strings.TrimPrefix(attr, xattrPrefix)] = string(data)
+	if err := f.cli.UpdateObjectMetadata(ctx, f.cli.opt.UserID, f.bucket, f.key, "", md); err != nil {
+		return errnoFromS3(err)
+	}
+	return 0
+}
+
+func (f *s3File) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	obj, err := f.cli.HeadObject(ctx, f.cli.opt.UserID, f.bucket, f.key)
+	if err != nil {
+		return 0, errnoFromS3(err)
+	}
+	var sb strings.Builder
+	sb.WriteString(xattrETag)
+	sb.WriteByte(0)
+	sb.WriteString(xattrStorageClass)
+	sb.WriteByte(0)
+	for k := range obj.Metadata {
+		sb.WriteString(xattrPrefix + k)
+		sb.WriteByte(0)
+	}
+	return xattrReply(dest, sb.String())
+}
+
+func (f *s3File) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	if f.cli.opt.ReadOnly {
+		return syscall.EROFS
+	}
+	if !strings.HasPrefix(attr, xattrPrefix) {
+		return syscall.EOPNOTSUPP
+	}
+	if attr == xattrETag || attr == xattrStorageClass {
+		return syscall.EPERM
+	}
+	obj, err := f.cli.HeadObject(ctx, f.cli.opt.UserID, f.bucket, f.key)
+	if err != nil {
+		return errnoFromS3(err)
+	}
+	key := strings.TrimPrefix(attr, xattrPrefix)
+	if _, ok := obj.Metadata[key]; !ok {
+		return syscall.ENODATA
+	}
+	md := make(map[string]string, len(obj.Metadata)-1)
+	for k, v := range obj.Metadata {
+		if k != key {
+			md[k] = v
+		}
+	}
+	if err := f.cli.UpdateObjectMetadata(ctx, f.cli.opt.UserID, f.bucket, f.key, "", md); err != nil {
+		return errnoFromS3(err)
+	}
+	return 0
+}